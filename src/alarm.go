@@ -3,48 +3,271 @@ package main
 import (
 	_ "embed"
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
-	"log"
 
 	"github.com/hajimehoshi/go-mp3"
-	"github.com/ebitengine/oto/v3"
 )
 
-// TODO: support different alarm types & make them configurable
 // NOTE: The audio files are embeded into the binary during the compilation (not a bad thing)
 
 //go:embed assets/alarm.mp3
-var alarm []byte
+var alarmSound []byte
 
-func PlayAlarm() {
-	go func() {
-		decoder, err := mp3.NewDecoder(bytes.NewReader(alarm))
+//go:embed assets/bell.wav
+var bellSound []byte
+
+//go:embed assets/beep.wav
+var beepSound []byte
+
+//go:embed assets/chime.wav
+var chimeSound []byte
+
+// The sounds that can be picked by name in the config, anything else is the path of a file
+var builtinSounds = map[string][]byte{
+	"alarm": alarmSound,
+	"bell":  bellSound,
+	"beep":  beepSound,
+	"chime": chimeSound,
+}
+
+// The sounds are decoded to go-mp3's format: signed 16bit integers, 2 channels at 44100Hz
+const (
+	soundSampleRate  int = 44100
+	soundChannels    int = 2
+	soundFrameSize   int = 4 // bytes per frame, a sample per channel
+
+	// Silence between the repetitions of an alarm
+	alarmGap  time.Duration = time.Millisecond * 300
+)
+
+var (
+	ErrUnknownSound        = errors.New("Unknown sound")
+	ErrUnsupportedWAV      = errors.New("Unsupported WAV file")
+	ErrFailedDecodingSound = errors.New("Failed decoding the sound")
+)
+
+// The decoded sounds by the config value they were loaded from
+var decodedSounds = map[string][]byte{}
+
+// Decodes a built-in sound or an MP3/WAV file, "none" is no sound
+func loadSound(value string) ([]byte, error) {
+	if value == "none" {
+		return nil, nil
+	}
+
+	if pcm, ok := decodedSounds[value]; ok {
+		return pcm, nil
+	}
+
+	data, ok := builtinSounds[value]
+	if !ok {
+		ext := strings.ToLower(filepath.Ext(value))
+		if ext != ".mp3" && ext != ".wav" {
+			return nil, fmt.Errorf("%w: %q isn't a built-in sound nor an MP3 or WAV file", ErrUnknownSound, value)
+		}
+
+		var err error
+		data, err = os.ReadFile(expandHome(value))
 		if err != nil {
-			log.Fatal("`assets/alarm.mp3` can't be decoded.")
+			return nil, fmt.Errorf("%w %q: %w", ErrFailedDecodingSound, value, err)
 		}
+	}
 
-		options := &oto.NewContextOptions{}
-		options.SampleRate = 44100	//  WTH IS THIS
-		options.ChannelCount = 2
-		options.Format = oto.FormatSignedInt16LE // format of the source. go-mp3's format is signed 16bit integers.
+	var pcm []byte
+	var err error
+	if bytes.HasPrefix(data, []byte("RIFF")) {
+		pcm, err = decodeWAV(data)
+	} else {
+		pcm, err = decodeMP3(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrFailedDecodingSound, value, err)
+	}
 
-		context, readyChan, err := oto.NewContext(options)
-		if err != nil {
-			return // HACK: User's sound didn't work for whatever reason (not my problem)
+	decodedSounds[value] = pcm
+	return pcm, nil
+}
+
+// Decodes the sound so a broken file is reported on startup, it falls back to defaultValue
+func validateSound(errsPtr *[]error, valuePtr *string, defaultValue string, key string) {
+	if _, err := loadSound(*valuePtr); err != nil {
+		*valuePtr = defaultValue
+		*errsPtr  = append(*errsPtr, fmt.Errorf("Invalid %s: %w", key, err))
+	}
+}
+
+func decodeMP3(data []byte) ([]byte, error) {
+	decoder, err := mp3.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	pcm, err := io.ReadAll(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	return resample(pcm, decoder.SampleRate()), nil
+}
+
+// Decodes a PCM WAV file of 8, 16 or 24 bit samples, the channels after the first two are dropped
+// and a mono sound is played on both
+func decodeWAV(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%w: not a WAVE file", ErrUnsupportedWAV)
+	}
+
+	var format, channels, bits uint16
+	var rate uint32
+	var samples []byte
+
+	// The file is a list of chunks: their ID, size and data padded to an even size
+	for rest := data[12:]; len(rest) >= 8; {
+		id   := string(rest[:4])
+		size := int(binary.LittleEndian.Uint32(rest[4:8]))
+		rest  = rest[8:]
+		if size > len(rest) {
+			size = len(rest) // some encoders write a wrong size for the last chunk
 		}
 
-		<-readyChan
+		switch (id) {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("%w: short fmt chunk", ErrUnsupportedWAV)
+			}
+			format   = binary.LittleEndian.Uint16(rest[0:2])
+			channels = binary.LittleEndian.Uint16(rest[2:4])
+			rate     = binary.LittleEndian.Uint32(rest[4:8])
+			bits     = binary.LittleEndian.Uint16(rest[14:16])
 
-		player := context.NewPlayer(decoder)
+			// WAVE_FORMAT_EXTENSIBLE has the real format at the start of its GUID
+			if format == 0xFFFE && size >= 26 {
+				format = binary.LittleEndian.Uint16(rest[24:26])
+			}
 
-		player.Play()
+		case "data":
+			samples = rest[:size]
+		}
+
+		rest = rest[min(size + size % 2, len(rest)):]
+	}
+
+	switch {
+	case format != 1:
+		return nil, fmt.Errorf("%w: only PCM is supported", ErrUnsupportedWAV)
+	case channels == 0 || rate == 0:
+		return nil, fmt.Errorf("%w: no fmt chunk", ErrUnsupportedWAV)
+	case bits != 8 && bits != 16 && bits != 24:
+		return nil, fmt.Errorf("%w: %d bit samples", ErrUnsupportedWAV, bits)
+	case samples == nil:
+		return nil, fmt.Errorf("%w: no data chunk", ErrUnsupportedWAV)
+	}
+
+	sampleSize := int(bits / 8)
+	frameSize  := sampleSize * int(channels)
+
+	sample := func(frame []byte, channel int) int16 {
+		s := frame[channel * sampleSize:]
+		switch (sampleSize) {
+		case 1:
+			return int16(int(s[0]) - 128) << 8 // 8 bit samples are unsigned
+		case 2:
+			return int16(binary.LittleEndian.Uint16(s))
+		}
+		return int16(binary.LittleEndian.Uint16(s[1:])) // the 16 most significant bits
+	}
+
+	frames := len(samples) / frameSize
+	pcm := make([]byte, frames * soundFrameSize)
 
-		for player.IsPlaying() {
-			time.Sleep(time.Millisecond)
+	for i := range frames {
+		frame := samples[i * frameSize:]
+		left  := sample(frame, 0)
+		right := left
+		if channels > 1 {
+			right = sample(frame, 1)
 		}
 
-		player.Close()
-	} ()
+		binary.LittleEndian.PutUint16(pcm[i * soundFrameSize:], uint16(left))
+		binary.LittleEndian.PutUint16(pcm[i * soundFrameSize + 2:], uint16(right))
+	}
+
+	return resample(pcm, int(rate)), nil
 }
 
+// Converts stereo 16bit PCM from the given sample rate to soundSampleRate by linear interpolation
+func resample(pcm []byte, rate int) []byte {
+	if rate == soundSampleRate || rate <= 0 {
+		return pcm
+	}
+
+	frames := len(pcm) / soundFrameSize
+	if frames == 0 {
+		return nil
+	}
+
+	sample := func(frame int, channel int) float64 {
+		frame = min(frame, frames - 1)
+		return float64(int16(binary.LittleEndian.Uint16(pcm[frame * soundFrameSize + channel * 2:])))
+	}
 
+	outFrames := int(int64(frames) * int64(soundSampleRate) / int64(rate))
+	out := make([]byte, outFrames * soundFrameSize)
+
+	for i := range outFrames {
+		pos  := float64(i) * float64(rate) / float64(soundSampleRate)
+		j    := int(pos)
+		frac := pos - float64(j)
+
+		for channel := range soundChannels {
+			s := sample(j, channel) * (1 - frac) + sample(j + 1, channel) * frac
+			binary.LittleEndian.PutUint16(out[i * soundFrameSize + channel * 2:], uint16(int16(s)))
+		}
+	}
+
+	return out
+}
+
+// The sound of the end of a phase of the given kind repeated as the config says, nil for no sound
+func alarmFor(kind phaseType) []byte {
+	value := Config.Alarm.BreakEnd
+	if kind == Focus {
+		value = Config.Alarm.FocusEnd
+	}
+
+	// The sounds of the config are decoded by LoadConfig so this can only fail without a config
+	sound, err := loadSound(value)
+	if err != nil || len(sound) == 0 {
+		return nil
+	}
+
+	gap := make([]byte, int(alarmGap.Seconds() * float64(soundSampleRate)) * soundFrameSize)
+
+	var pcm []byte
+	for i := range int(Config.Alarm.Repeat) {
+		if i != 0 {
+			pcm = append(pcm, gap...)
+		}
+		pcm = append(pcm, sound...)
+	}
+
+	return pcm
+}
+
+// Queues the alarm of the end of a phase of the given kind, it returns false when it has no sound
+func PlayAlarm(kind phaseType) bool {
+	pcm := alarmFor(kind)
+	if pcm == nil {
+		return false
+	}
+
+	getAudio().play(pcm, float64(Config.Alarm.Volume) / 100)
+	return true
+}
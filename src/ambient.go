@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"math/rand/v2"
+)
+
+// The ambient sounds loop under the alarms while the timer runs, they're generated as they're
+// played so there are no assets to ship: a ticking clock at a given BPM or noise
+
+// The sounds the phases can have, "none" is silence
+var ambientSounds = []string{"none", "tick", "white", "pink", "brown"}
+
+const (
+	tickLength  float64 = 0.015 // seconds of a tick
+	tickFreq    float64 = 1800  // Hz, the tock is lower
+	tockFreq    float64 = 1400
+)
+
+// Fills p with as many frames as it fits of a mono signal played on both channels
+func fillFrames(p []byte, next func() float64) int {
+	frames := len(p) / soundFrameSize
+
+	for i := range frames {
+		s := uint16(int16(max(min(next(), 1), -1) * math.MaxInt16))
+		binary.LittleEndian.PutUint16(p[i * soundFrameSize:], s)
+		binary.LittleEndian.PutUint16(p[i * soundFrameSize + 2:], s)
+	}
+
+	return frames * soundFrameSize
+}
+
+// A clock ticking at bpm beats per minute, every other beat is a tock
+type tickSource struct {
+	bpm    uint16
+	frame  int
+}
+
+func (s *tickSource) Read(p []byte) (int, error) {
+	period := soundSampleRate * 60 / int(s.bpm)
+
+	return fillFrames(p, func() float64 {
+		pos  := s.frame % period
+		beat := s.frame / period
+		s.frame++
+
+		t := float64(pos) / float64(soundSampleRate)
+		if t >= tickLength {
+			return 0
+		}
+
+		freq := tickFreq
+		if beat % 2 == 1 {
+			freq = tockFreq
+		}
+
+		return 0.8 * math.Sin(2 * math.Pi * freq * t) * math.Exp(-t * 300)
+	}), nil
+}
+
+// White noise or noise filtered to pink (-3dB per octave) or brown (-6dB per octave)
+type noiseSource struct {
+	color  string
+	pink   [7]float64 // the state of the pink filter
+	brown  float64
+}
+
+func (s *noiseSource) Read(p []byte) (int, error) {
+	return fillFrames(p, s.next), nil
+}
+
+func (s *noiseSource) next() float64 {
+	white := rand.Float64() * 2 - 1
+
+	switch (s.color) {
+	case "pink":
+		// Paul Kellet's refined filter
+		b := &s.pink
+		b[0] = 0.99886 * b[0] + white * 0.0555179
+		b[1] = 0.99332 * b[1] + white * 0.0750759
+		b[2] = 0.96900 * b[2] + white * 0.1538520
+		b[3] = 0.86650 * b[3] + white * 0.3104856
+		b[4] = 0.55000 * b[4] + white * 0.5329522
+		b[5] = -0.7616 * b[5] - white * 0.0168980
+		pink := b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + white * 0.5362
+		b[6] = white * 0.115926
+		return pink * 0.11
+
+	case "brown":
+		// Integrated white noise leaking back to 0 so it doesn't drift away
+		s.brown = (s.brown + 0.02 * white) / 1.02
+		return s.brown * 3.5
+	}
+
+	return white * 0.5
+}
+
+// The generator of an ambient sound, nil for "none"
+func newAmbientSource(name string) io.Reader {
+	switch (name) {
+	case "tick":
+		return &tickSource{bpm: Config.Ambient.BPM}
+	case "white", "pink", "brown":
+		return &noiseSource{color: name}
+	}
+
+	return nil
+}
+
+// The ambient sound the current phase should have now
+func (m *PomodoroModel) ambientSound() string {
+	if m.muted || !m.running {
+		return "none"
+	}
+
+	switch (m.getPhase().kind) {
+	case ShortBreak:
+		return Config.Ambient.ShortBreak
+	case LongBreak:
+		return Config.Ambient.LongBreak
+	}
+
+	return Config.Ambient.Focus
+}
+
+// Starts or stops the ambient sound when the phase, the running state or muting changed it. The
+// daemon plays it when the TUI is attached to one
+func (m *PomodoroModel) syncAmbient() {
+	if m.remote != nil {
+		return
+	}
+
+	sound := m.ambientSound()
+	if sound == m.ambient {
+		return
+	}
+
+	// Nothing was played yet so there is nothing to stop
+	if m.ambient == "" && sound == "none" {
+		m.ambient = sound
+		return
+	}
+
+	m.ambient = sound
+	getAudio().setAmbient(newAmbientSource(sound), float64(Config.Ambient.Volume) / 100)
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The HTTP API controls the same timer as the keys, the requests are handed to the loop owning the
+// PomodoroModel (bubbletea's through Program.Send or the daemon's) and answered like the daemon's
+// socket does:
+//
+//	GET  /state                 the current state
+//	POST /toggle, /skip, /reset
+//	POST /start?phase=<name>    starts the current phase or skips to the next one with that name or kind
+//	GET  /events                the events of the log as Server-Sent Events
+//	GET  /metrics               the state and the totals of the log for Prometheus, see metrics.go
+//
+// It's only served on the loopback or a unix socket, and browsers can only use it from the origins
+// of the config
+
+// Sent to the TUI by the API, the response is sent back on Reply
+type APIRequestMsg struct {
+	Request  daemonRequest
+	Reply    chan daemonResponse
+}
+
+type apiServer struct {
+	// Hands a request to the loop owning the model and waits for its response
+	send         func(ctx context.Context, request daemonRequest) (daemonResponse, error)
+
+	mu           sync.Mutex
+	server       *http.Server // nil until it's listening
+	subscribers  map[chan daemonResponse]bool
+	totals       metricsTotals
+}
+
+var (
+	ErrInvalidListen  = errors.New("Invalid http.listen")
+	ErrAPIListening   = errors.New("Failed serving the HTTP API")
+)
+
+const apiKeepAlive time.Duration = time.Second * 30
+
+func newAPIServer(send func(ctx context.Context, request daemonRequest) (daemonResponse, error)) *apiServer {
+	return &apiServer{
+		send:        send,
+		subscribers: map[chan daemonResponse]bool{},
+	}
+}
+
+// Checks the address is on the loopback or a unix socket, "unix:<path>"
+func checkListen(address string) error {
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		if path == "" {
+			return fmt.Errorf("%w: the unix socket needs a path", ErrInvalidListen)
+		}
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidListen, err)
+	}
+
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("%w: %q isn't a loopback address", ErrInvalidListen, host)
+	}
+
+	return nil
+}
+
+func validateListen(errsPtr *[]error, valuePtr *string, defaultValue string) {
+	if err := checkListen(*valuePtr); err != nil {
+		*valuePtr = defaultValue
+		*errsPtr  = append(*errsPtr, err)
+	}
+}
+
+// Starts serving in the background, it does nothing when it's already serving
+func (a *apiServer) listen() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.server != nil {
+		return nil
+	}
+
+	var listener net.Listener
+	var err error
+
+	if path, ok := strings.CutPrefix(Config.HTTP.Listen, "unix:"); ok {
+		path = expandHome(path)
+		if err := removeStaleSocket(path); err != nil {
+			return fmt.Errorf("%w: %w", ErrAPIListening, err)
+		}
+		listener, err = net.Listen("unix", path)
+	} else {
+		listener, err = net.Listen("tcp", Config.HTTP.Listen)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAPIListening, err)
+	}
+
+	// NOTE: there is nothing to count yet if the log can't be read
+	a.totals = metricsTotals{}
+	if events, err := readAllEvents(); err == nil {
+		a.totals.add(events)
+	}
+
+	a.server = &http.Server{Handler: a.handler()}
+	go a.server.Serve(listener)
+
+	return nil
+}
+
+// Removes the socket left by a run that didn't exit cleanly, like the daemon does. A socket someone
+// listens to or a file that isn't a socket is kept
+func removeStaleSocket(path string) error {
+	stat, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if stat.Mode().Type() != os.ModeSocket {
+		return fmt.Errorf("%s already exists and isn't a socket", path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is already in use", path)
+	}
+
+	return os.Remove(path)
+}
+
+func (a *apiServer) close() {
+	a.mu.Lock()
+	server := a.server
+	a.server = nil
+
+	for events := range a.subscribers {
+		delete(a.subscribers, events)
+		close(events)
+	}
+	a.mu.Unlock()
+
+	if server != nil {
+		server.Close()
+	}
+}
+
+func (a *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /state", a.command("status"))
+	mux.HandleFunc("POST /toggle", a.command("toggle"))
+	mux.HandleFunc("POST /skip", a.command("skip"))
+	mux.HandleFunc("POST /reset", a.command("reset"))
+	mux.HandleFunc("POST /start", a.command("start"))
+	mux.HandleFunc("GET /events", a.events)
+	mux.HandleFunc("GET /metrics", a.metrics)
+
+	return a.guard(mux)
+}
+
+// Refuses the requests of web pages: the ones from other origins than the config's and the ones
+// for another host than the loopback (DNS rebinding)
+func (a *apiServer) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !slices.Contains(Config.HTTP.Origins, origin) {
+			writeAPIResponse(w, http.StatusForbidden, daemonResponse{Error: "Origin not allowed"})
+			return
+		}
+
+		if !strings.HasPrefix(Config.HTTP.Listen, "unix:") {
+			host, _, err := net.SplitHostPort(r.Host)
+			if err != nil {
+				host = r.Host
+			}
+			if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+				writeAPIResponse(w, http.StatusForbidden, daemonResponse{Error: "Host not allowed"})
+				return
+			}
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeAPIResponse(w http.ResponseWriter, status int, response daemonResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// The handler of an action of the timer, the phase of /start is its argument
+func (a *apiServer) command(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := daemonRequest{Cmd: action}
+		if phase := r.URL.Query().Get("phase"); action == "start" && phase != "" {
+			request.Args = []string{phase}
+		}
+
+		response, err := a.send(r.Context(), request)
+		switch {
+		case err != nil:
+			writeAPIResponse(w, http.StatusServiceUnavailable, daemonResponse{Error: err.Error()})
+		case !response.Ok:
+			writeAPIResponse(w, http.StatusConflict, response)
+		default:
+			writeAPIResponse(w, http.StatusOK, response)
+		}
+	}
+}
+
+// Streams the events with the state after them, starting with the current state as a "state" event
+func (a *apiServer) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIResponse(w, http.StatusInternalServerError, daemonResponse{Error: "Streaming not supported"})
+		return
+	}
+
+	current, err := a.send(r.Context(), daemonRequest{Cmd: "status"})
+	if err != nil {
+		writeAPIResponse(w, http.StatusServiceUnavailable, daemonResponse{Error: err.Error()})
+		return
+	}
+	current.Event = "state"
+
+	events := make(chan daemonResponse, 64)
+	a.mu.Lock()
+	a.subscribers[events] = true
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		if a.subscribers[events] {
+			delete(a.subscribers, events)
+			close(events)
+		}
+		a.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(response daemonResponse) error {
+		data, _ := json.Marshal(response)
+		_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", response.Event, data)
+		flusher.Flush()
+		return err
+	}
+
+	if send(current) != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(apiKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case response, ok := <-events:
+			if !ok || send(response) != nil {
+				return
+			}
+
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Sends the events to the /events streams with the state after each of them and counts them in the
+// metrics, eventState is the model's
+func (a *apiServer) publish(events []pomodoroEvent, eventState func(e pomodoroEvent) PomodoroState) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.totals.add(events)
+
+	for _, e := range events {
+		state := eventState(e)
+
+		for subscriber := range a.subscribers {
+			select {
+			case subscriber <- daemonResponse{Ok: true, Event: e.event, State: &state}:
+			default:
+				// Too slow to keep up, it has to connect again
+				delete(a.subscribers, subscriber)
+				close(subscriber)
+			}
+		}
+	}
+}
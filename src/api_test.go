@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Each event written with others is sent with the state right after it and not the last one
+func TestPublishEventState(t *testing.T) {
+	m, clock := newTestModel(t, nil)
+	m.setRunning(true)
+	m.flush()
+
+	run(m, clock, time.Minute * 25)
+
+	a := newAPIServer(nil)
+	subscriber := make(chan daemonResponse, 8)
+	a.subscribers[subscriber] = true
+
+	events := m.pending
+	a.publish(events, m.eventState)
+
+	if len(events) < 2 {
+		t.Fatalf("%d events written at the end of the focus phase, want at least 2", len(events))
+	}
+
+	for _, e := range events {
+		response := <-subscriber
+		state := response.State
+
+		if response.Event != e.event || state.Phase != e.phase || state.N != e.n ||
+			state.Remaining != e.remainingTime.Seconds() || state.Running != e.running {
+			t.Errorf("%s sent with %s #%d remaining %gs running %v, want %s #%d %gs %v", response.Event,
+				state.Phase, state.N, state.Remaining, state.Running, e.phase, e.n, e.remainingTime.Seconds(), e.running)
+		}
+	}
+
+	if first := events[0]; first.event != CompletedEvent || first.kind != Focus {
+		t.Errorf("first event %s of a %s phase, want the focus phase completed", first.event, first.kind)
+	}
+}
+
+// Only a socket nobody listens to is replaced by the API's
+func TestListenUnixSocket(t *testing.T) {
+	tests := []struct {
+		name    string
+		create  func(t *testing.T, path string)
+		err     bool
+	}{
+		{"nothing", func(t *testing.T, path string) {}, false},
+		{"stale socket", func(t *testing.T, path string) {
+			listener, err := net.Listen("unix", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			listener.(*net.UnixListener).SetUnlinkOnClose(false)
+			listener.Close()
+		}, false},
+		{"live socket", func(t *testing.T, path string) {
+			listener, err := net.Listen("unix", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { listener.Close() })
+		}, true},
+		{"regular file", func(t *testing.T, path string) {
+			if err := os.WriteFile(path, []byte("notes"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useConfig(t, defaultConfig)
+			useLog(t)
+
+			path := filepath.Join(t.TempDir(), "api.sock")
+			Config.HTTP.Listen = "unix:" + path
+			test.create(t, path)
+
+			a := newAPIServer(nil)
+			err := a.listen()
+			defer a.close()
+
+			if failed := err != nil; failed != test.err {
+				t.Fatalf("listen() = %v, want an error: %v", err, test.err)
+			}
+			if test.name == "regular file" {
+				if data, _ := os.ReadFile(path); string(data) != "notes" {
+					t.Errorf("the file at the socket's path was replaced")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ebitengine/oto/v3"
+)
+
+// The audio is played by a single engine started on the first sound: oto only allows one context
+// per process so it's made once and every sound is a player of it. The sounds are queued and
+// played one after the other, when there is no audio device they go to a null backend. An ambient
+// sound can loop under them
+
+// Where the sounds are played, the backend mixes the sounds played at the same time
+type audioBackend interface {
+	play(source io.Reader, volume float64) audioPlayback // played until source ends
+}
+
+// A sound being played
+type audioPlayback interface {
+	wait() // blocks until the sound is over or stopped
+	stop()
+}
+
+type audioSound struct {
+	pcm     []byte // signed 16bit integers, soundChannels channels at soundSampleRate
+	volume  float64 // from 0 to 1
+}
+
+type audioEngine struct {
+	mu          sync.Mutex
+	queue       []audioSound
+	current     *audioSound
+	playback    audioPlayback
+	snoozed     *time.Timer
+	wake        chan struct{}
+
+	backend     audioBackend // set once it's ready
+	ready       chan struct{} // closed when the backend is ready
+	ambient     audioPlayback // nil when there is no ambient sound
+	ambientGen  int // incremented on every change of the ambient sound
+}
+
+var (
+	audio      = newAudioEngine()
+	audioOnce  sync.Once
+)
+
+func newAudioEngine() *audioEngine {
+	return &audioEngine{
+		wake:  make(chan struct{}, 1),
+		ready: make(chan struct{}),
+	}
+}
+
+// Starts the engine the first time it's called
+func getAudio() *audioEngine {
+	audioOnce.Do(func() { go audio.loop(newAudioBackend) })
+	return audio
+}
+
+// Queues a sound, it's played after the ones before it
+func (a *audioEngine) play(pcm []byte, volume float64) {
+	a.mu.Lock()
+	a.queue = append(a.queue, audioSound{pcm: pcm, volume: volume})
+	a.mu.Unlock()
+
+	a.signal()
+}
+
+// Stops the sound being played and drops the queued and snoozed ones
+func (a *audioEngine) stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.snoozed != nil {
+		a.snoozed.Stop()
+		a.snoozed = nil
+	}
+	a.stopLocked()
+}
+
+// Stops the sound being played and the queued ones then plays them again after d, it returns
+// false when there is nothing to snooze
+func (a *audioEngine) snooze(d time.Duration) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var sounds []audioSound
+	if a.current != nil {
+		sounds = append(sounds, *a.current)
+	}
+	sounds = append(sounds, a.queue...)
+
+	if len(sounds) == 0 {
+		return false
+	}
+	a.stopLocked()
+
+	// Snoozing again only delays the sounds snoozed before
+	if a.snoozed != nil {
+		a.snoozed.Stop()
+	}
+	a.snoozed = time.AfterFunc(d, func() {
+		a.mu.Lock()
+		a.queue   = append(sounds, a.queue...)
+		a.snoozed = nil
+		a.mu.Unlock()
+
+		a.signal()
+	})
+
+	return true
+}
+
+// Replaces the ambient sound with the endless source, a nil source stops it
+func (a *audioEngine) setAmbient(source io.Reader, volume float64) {
+	a.mu.Lock()
+	a.ambientGen++
+	gen := a.ambientGen
+	a.mu.Unlock()
+
+	// Applied once the backend is ready without blocking the caller
+	go func() {
+		<-a.ready
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		if gen != a.ambientGen {
+			return // it was changed again meanwhile
+		}
+
+		if a.ambient != nil {
+			a.ambient.stop()
+			a.ambient.wait()
+			a.ambient = nil
+		}
+
+		if source != nil {
+			a.ambient = a.backend.play(source, volume)
+		}
+	}()
+}
+
+func (a *audioEngine) stopLocked() {
+	a.queue = nil
+	if a.playback != nil {
+		a.playback.stop()
+	}
+}
+
+func (a *audioEngine) signal() {
+	select {
+	case a.wake <- struct{}{}:
+	default: // the loop is already woken up
+	}
+}
+
+// Plays the queued sounds as they come, the backend is made here so starting the engine doesn't
+// wait for the audio device
+func (a *audioEngine) loop(newBackend func() audioBackend) {
+	backend := newBackend()
+
+	a.mu.Lock()
+	a.backend = backend
+	a.mu.Unlock()
+	close(a.ready)
+
+	for range a.wake {
+		for {
+			a.mu.Lock()
+			if len(a.queue) == 0 {
+				a.mu.Unlock()
+				break
+			}
+
+			sound := a.queue[0]
+			a.queue    = a.queue[1:]
+			a.current  = &sound
+			a.playback = backend.play(bytes.NewReader(sound.pcm), sound.volume)
+			playback  := a.playback
+			a.mu.Unlock()
+
+			playback.wait()
+
+			a.mu.Lock()
+			a.current  = nil
+			a.playback = nil
+			a.mu.Unlock()
+		}
+	}
+}
+
+// oto's backend if there is an audio device, the null one otherwise
+func newAudioBackend() audioBackend {
+	options := &oto.NewContextOptions{}
+	options.SampleRate = soundSampleRate
+	options.ChannelCount = soundChannels
+	options.Format = oto.FormatSignedInt16LE // format of the source. go-mp3's format is signed 16bit integers.
+
+	context, readyChan, err := oto.NewContext(options)
+	if err != nil {
+		return nullAudio{} // e.g. a headless server or CI
+	}
+
+	<-readyChan
+
+	return &otoAudio{context: context}
+}
+
+
+type otoAudio struct {
+	context *oto.Context
+}
+
+type otoPlayback struct {
+	player *oto.Player
+}
+
+func (o *otoAudio) play(source io.Reader, volume float64) audioPlayback {
+	player := o.context.NewPlayer(source)
+	player.SetVolume(volume)
+	player.Play()
+
+	return otoPlayback{player: player}
+}
+
+func (p otoPlayback) wait() {
+	for p.player.IsPlaying() {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	p.player.Close()
+}
+
+func (p otoPlayback) stop() {
+	p.player.Pause()
+}
+
+
+// Discards the sounds
+type nullAudio struct{}
+
+type nullPlayback struct{}
+
+func (nullAudio) play(source io.Reader, volume float64) audioPlayback {
+	return nullPlayback{}
+}
+
+func (nullPlayback) wait() {}
+
+func (nullPlayback) stop() {}
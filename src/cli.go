@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// NOTE: plumadoro without a subcommand is the same as `plumadoro start`
+
+type command struct {
+	name     string
+	usage    string
+	run      func(args []string) error
+}
+
+// Flags shared by all of the subcommands, they override the config for the current run only
+type cliOptions struct {
+	configPath   string
+	logPath      string
+	profile      string
+
+	focus        time.Duration
+	shortBreak   time.Duration
+	longBreak    time.Duration
+	autostart    bool
+}
+
+var ErrUnknownCommand = errors.New("Unknown command")
+
+var commands []command
+
+func init() {
+	// Assigned in init() because `help` reads the commands table itself
+	commands = []command{
+		{"start",        "Start the pomodoro TUI (default)",          runStart},
+		{"status",       "Print the current phase and remaining time", runStatus},
+		{"stats",        "Print a summary of the log file",            runStats},
+		{"config check", "Validate the configuration file",            runConfigCheck},
+		{"daemon",       "Run the timer in the background",            runDaemon},
+		{"help",         "Show this help",                             runHelp},
+	}
+}
+
+func newFlagSet(name string, opts *cliOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	fs.StringVar(&opts.configPath, "config", "", "path of the TOML config file")
+	fs.StringVar(&opts.logPath,    "log",    "", "path of the CSV log file")
+	fs.StringVar(&opts.profile,    "profile", "", "name of the profile to use")
+
+	fs.DurationVar(&opts.focus,      "focus",       0, "focus duration for this run (e.g. 50m)")
+	fs.DurationVar(&opts.shortBreak, "short-break", 0, "short break duration for this run")
+	fs.DurationVar(&opts.longBreak,  "long-break",  0, "long break duration for this run")
+	fs.BoolVar(&opts.autostart,      "autostart",   false, "start phases automatically for this run")
+
+	return fs
+}
+
+// Parses the flags then loads the config and applies the flags that were set on top of it,
+// the returned error is the LoadConfig error (it isn't fatal since Config falls back to defaults).
+// flags adds the flags only a subcommand has, it can be nil
+func setup(name string, args []string, flags func(fs *flag.FlagSet)) (fs *flag.FlagSet, configErr error, err error) {
+	var opts cliOptions
+
+	fs = newFlagSet(name, &opts)
+	if flags != nil {
+		flags(fs)
+	}
+
+	if err = fs.Parse(args); err != nil {
+		return fs, nil, err
+	}
+
+	if opts.configPath != "" {
+		configPaths = []string{opts.configPath}
+	}
+
+	if opts.logPath != "" {
+		logPath = opts.logPath
+	}
+
+	configErr = LoadConfig()
+
+	var errs []error
+
+	// The profile is applied first so the other flags override it
+	if opts.profile != "" {
+		if err := UseProfile(opts.profile); err != nil {
+			return fs, configErr, err
+		}
+		Config.profileFromFlag = true
+	}
+
+	// NOTE: they're kept apart so switching to another profile (the restored one, the picker's...)
+	// doesn't drop them
+	fs.Visit(func(f *flag.Flag) {
+		switch (f.Name) {
+		case "focus":
+			validateRange(&errs, &opts.focus, time.Second*1, time.Minute*1000, Config.Durations.Focus, "--focus")
+			Config.overrides.Focus = &opts.focus
+		case "short-break":
+			validateRange(&errs, &opts.shortBreak, time.Second*1, time.Minute*1000, Config.Durations.ShortBreak, "--short-break")
+			Config.overrides.ShortBreak = &opts.shortBreak
+		case "long-break":
+			validateRange(&errs, &opts.longBreak, time.Second*1, time.Minute*1000, Config.Durations.LongBreak, "--long-break")
+			Config.overrides.LongBreak = &opts.longBreak
+		case "autostart":
+			Config.overrides.Autostart = &opts.autostart
+		}
+	})
+	Config.overrides.apply(&Config.ProfileConfigT)
+
+	return fs, configErr, errors.Join(errs...)
+}
+
+func runStart(args []string) error {
+	fs, configErr, err := setup("start", args, nil)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return fmt.Errorf("%w: unexpected arguments %q", ErrUnknownCommand, fs.Args())
+	}
+
+	// The TUI attaches to the daemon if it's running
+	remote, _ := attachDaemon()
+
+	model := &MainModel{configErr: configErr, remote: remote}
+	p := tea.NewProgram(model,
+		tea.WithAltScreen(),
+		tea.WithMouseCellMotion(),
+	)
+
+	// The API's requests go through the bubbletea loop so they don't race with Update
+	if Config.HTTP.Enabled {
+		model.api = newAPIServer(func(ctx context.Context, request daemonRequest) (daemonResponse, error) {
+			reply := make(chan daemonResponse, 1)
+			go p.Send(APIRequestMsg{Request: request, Reply: reply}) // it waits for the program to start
+
+			select {
+			case response := <-reply:
+				return response, nil
+			case <-ctx.Done():
+				return daemonResponse{}, ctx.Err()
+			}
+		})
+		defer model.api.close()
+	}
+
+	// The terminal was closed
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	go func() {
+		if _, ok := <-hangup; ok {
+			p.Send(tea.QuitMsg{})
+		}
+	}()
+
+	_, err = p.Run()
+
+	// NOTE: bubbletea stops on SIGTERM & SIGINT without going through Update, so the quit is logged
+	// here when the timer didn't log it itself
+	if model.pomodoro != nil {
+		model.pomodoro.logQuit()
+	}
+	hooks.wait()
+
+	return err
+}
+
+func runConfigCheck(args []string) error {
+	_, configErr, err := setup("config check", args, nil)
+	if err != nil {
+		return err
+	}
+
+	if configErr != nil {
+		return configErr
+	}
+
+	fmt.Printf("%s: OK\n", Config.loadedConfigPath)
+	return nil
+}
+
+func runHelp(args []string) error {
+	printUsage(os.Stdout)
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: plumadoro [command] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+
+	fs := newFlagSet("", &cliOptions{})
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// Finds the command whose words prefix args ("config check" is two words)
+func findCommand(args []string) (command, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return commands[0], args, nil
+	}
+
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) < len(words) {
+			continue
+		}
+
+		if strings.Join(args[:len(words)], " ") == c.name {
+			return c, args[len(words):], nil
+		}
+	}
+
+	return command{}, nil, fmt.Errorf("%w: %q", ErrUnknownCommand, strings.Join(args, " "))
+}
+
+func RunCLI(args []string) error {
+	c, rest, err := findCommand(args)
+	if err != nil {
+		printUsage(os.Stderr)
+		return err
+	}
+
+	err = c.run(rest)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+
+	return err
+}
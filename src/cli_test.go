@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Loads the config file of the test with the flags
+func setupConfig(t *testing.T, config string, args ...string) {
+	t.Helper()
+
+	useConfig(t, defaultConfig)
+
+	path := filepath.Join(t.TempDir(), "plumadoro.toml")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := configPaths
+	t.Cleanup(func() { configPaths = paths })
+
+	_, configErr, err := setup("start", append([]string{"--config", path}, args...), nil)
+	if configErr != nil || err != nil {
+		t.Fatalf("setup() = %v, %v", configErr, err)
+	}
+}
+
+// The flags stay over the profiles used after the startup one, e.g. the one of the log
+func TestOverridesKeptAcrossProfiles(t *testing.T) {
+	setupConfig(t, `
+[profiles.deep_work]
+auto_start = false
+[profiles.deep_work.durations]
+focus = "90m"
+short_break = "15m"
+`, "--focus", "50m", "--autostart")
+
+	for _, profile := range []string{"deep_work", DefaultProfile} {
+		if err := UseProfile(profile); err != nil {
+			t.Fatal(err)
+		}
+
+		if Config.Durations.Focus != time.Minute * 50 || !Config.Autostart {
+			t.Errorf("%s: focus %s autostart %v, want the flags' 50m and true", profile,
+				Config.Durations.Focus, Config.Autostart)
+		}
+	}
+
+	if err := UseProfile("deep_work"); err != nil {
+		t.Fatal(err)
+	}
+	if Config.Durations.ShortBreak != time.Minute * 15 {
+		t.Errorf("short break %s, want the profile's 15m", Config.Durations.ShortBreak)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"time"
+)
+
+// Where the timer and the log take the time from, it's swappable so the timer can be driven
+// by a fake clock instead of waiting for the real one
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+// NOTE: the monotonic reading is stripped, it stops while the machine sleeps so the times compared
+// with it wouldn't see the sleep and the timer would lose it
+func (systemClock) Now() time.Time {
+	return time.Now().Round(0)
+}
+
+var SystemClock Clock = systemClock{}
+
+// Whether two times are in the same day of the local time zone
+func sameDay(a time.Time, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// A clock only moving when it's told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, time.March, 2, 9, 0, 0, 0, time.Local)}
+}
+
+// Writes the log of the test to a temporary directory
+func useLog(t *testing.T) {
+	t.Helper()
+
+	path := logPath
+	logPath = filepath.Join(t.TempDir(), "plumadoro_log.csv")
+	t.Cleanup(func() { logPath = path })
+}
+
+// A timer on a fake clock with the default config, a log of its own and no alarms or notifications
+func newTestModel(t *testing.T, configure func(c *ConfigT)) (*PomodoroModel, *fakeClock) {
+	t.Helper()
+
+	config := defaultConfig
+	config.Notifications.Enabled = false
+	if configure != nil {
+		configure(&config)
+	}
+	useConfig(t, config)
+	useLog(t)
+
+	clock := newFakeClock()
+	m := NewPomodoroModel(clock)
+	m.alarm = func(kind phaseType) bool { return false }
+	m.startOver()
+	m.lastTick = clock.Now()
+
+	return m, clock
+}
+
+// Ticks every second for d like the TUI does
+func run(m *PomodoroModel, clock *fakeClock, d time.Duration) {
+	for end := clock.Now().Add(d); clock.Now().Before(end); {
+		clock.advance(time.Second)
+		m.tick(clock.Now())
+	}
+}
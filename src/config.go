@@ -6,6 +6,8 @@ import (
 	"time"
 	"errors"
 	"strconv"
+	"slices"
+	"text/template"
 	"golang.org/x/exp/constraints"
 	toml "github.com/BurntSushi/toml"
 )
@@ -14,15 +16,128 @@ type (
 	ConfigT struct {
 		TickDuration       time.Duration   `toml:"tick_duration"`
 		MaxPauseDuration   time.Duration   `toml:"max_pause_duration"` // NOTE: this max is per phase
-		Autostart          bool            `toml:"auto_start"`
 		Skipping           bool            `toml:"skipping"` // Allow skipping for phases
 		Pausing            bool            `toml:"pausing"` // Allow pausing
 
-		ProgressBar         ProgressBarConfigT  `toml:"progress_bar"`
-		Durations           DurationsConfigT    `toml:"durations"`
+		Log                 LogConfigT            `toml:"log"`
+		Goals               GoalsConfigT          `toml:"goals"`
+		Tasks               TasksConfigT          `toml:"tasks"`
+		Keys                KeysConfigT           `toml:"keys"`
+		Alarm               AlarmConfigT          `toml:"alarm"`
+		Ambient             AmbientConfigT        `toml:"ambient"`
+		Notifications       NotificationsConfigT  `toml:"notifications"`
+		Hooks               HooksConfigT          `toml:"hooks"`
+		HTTP                HTTPConfigT           `toml:"http"`
+		Webhooks            []WebhookConfigT      `toml:"webhooks"`
+
+		// The options of the active profile, the top level ones are the "default" profile
+		ProfileConfigT
+
+		Profile             string                     `toml:"profile"` // Profile used on startup
+		Profiles            map[string]toml.Primitive  `toml:"profiles"` // Decoded over the default profile
 
 		loadedConfig        bool // was LoadConfig called before
 		loadedConfigPath    string
+		profiles            map[string]ProfileConfigT
+		profileFromFlag     bool // the profile was picked with --profile
+		overrides           ProfileOverridesT
+	}
+
+	// The options of the profile given on the command line, they're applied over every profile used
+	// during the run. The nil ones weren't given
+	ProfileOverridesT struct {
+		Focus       *time.Duration
+		ShortBreak  *time.Duration
+		LongBreak   *time.Duration
+		Autostart   *bool
+	}
+
+	LogConfigT struct {
+		Rotation     string   `toml:"rotation"` // "monthly", "size" or "none"
+		MaxSize      uint64   `toml:"max_size"` // in KiB, used by the "size" rotation
+	}
+
+	// A zero target isn't part of the goal, a goal is met when all of its targets are
+	GoalsConfigT struct {
+		DailyPomodoros   uint16          `toml:"daily_pomodoros"`
+		DailyFocus       time.Duration   `toml:"daily_focus"`
+		WeeklyPomodoros  uint16          `toml:"weekly_pomodoros"`
+		WeeklyFocus      time.Duration   `toml:"weekly_focus"`
+	}
+
+	// A sound is a built-in one ("alarm", "bell", "beep" or "chime"), the path of an MP3 or WAV file
+	// or "none"
+	AlarmConfigT struct {
+		FocusEnd  string          `toml:"focus_end"` // played when a focus phase ends
+		BreakEnd  string          `toml:"break_end"` // played when a break ends
+		Volume    uint8           `toml:"volume"` // percent
+		Repeat    uint8           `toml:"repeat"` // times the sound is played
+		Snooze    time.Duration   `toml:"snooze"` // how long a snoozed alarm waits to play again
+	}
+
+	// The sound looping while a phase of each kind runs: "none", "tick", "white", "pink" or "brown"
+	AmbientConfigT struct {
+		Focus       string  `toml:"focus"`
+		ShortBreak  string  `toml:"short_break"`
+		LongBreak   string  `toml:"long_break"`
+		BPM         uint16  `toml:"bpm"` // of the ticking
+		Volume      uint8   `toml:"volume"` // percent
+	}
+
+	// Desktop notifications on the start of every phase
+	NotificationsConfigT struct {
+		Enabled       bool    `toml:"enabled"`
+		FocusUrgency  string  `toml:"focus_urgency"` // "low", "normal" or "critical"
+		BreakUrgency  string  `toml:"break_urgency"`
+	}
+
+	// Commands run by sh on the events of the timer, empty ones are skipped
+	HooksConfigT struct {
+		OnFocusStart  string          `toml:"on_focus_start"`
+		OnBreakStart  string          `toml:"on_break_start"`
+		OnPause       string          `toml:"on_pause"`
+		OnResume      string          `toml:"on_resume"`
+		OnSkip        string          `toml:"on_skip"`
+		OnReset       string          `toml:"on_reset"`
+		OnQuit        string          `toml:"on_quit"`
+		Timeout       time.Duration   `toml:"timeout"` // a hook is killed after it
+	}
+
+	// The HTTP API, see api.go
+	HTTPConfigT struct {
+		Enabled  bool      `toml:"enabled"`
+		Listen   string    `toml:"listen"` // host:port on the loopback or unix:<path>
+		Origins  []string  `toml:"origins"` // the origins browsers can use it from, e.g. an extension's
+	}
+
+	// A request sent on the events of the timer, see webhooks.go
+	WebhookConfigT struct {
+		Name     string             `toml:"name"` // shown in the warnings, the host of the URL by default
+		URL      string             `toml:"url"`
+		Events   []string           `toml:"events"`
+		Method   string             `toml:"method"`
+		Headers  map[string]string  `toml:"headers"`
+		Body     string             `toml:"body"` // a text/template of the JSON body, the event's JSON by default
+		Timeout  time.Duration      `toml:"timeout"` // of a single attempt
+		Retries  uint8              `toml:"retries"` // after the server answered with an error
+		Backoff  time.Duration      `toml:"backoff"` // before the first retry, it doubles after every failure
+
+		template *template.Template
+	}
+
+	TasksConfigT struct {
+		Source       string   `toml:"source"` // "plumadoro", "todotxt" or "taskwarrior"
+		TodoTxt      string   `toml:"todo_txt"` // path of the todo.txt file
+		Taskwarrior  string   `toml:"taskwarrior"` // Taskwarrior's command
+	}
+
+	// The options a profile can override, keys it doesn't set are the ones of the default profile
+	ProfileConfigT struct {
+		Autostart           bool                `toml:"auto_start"`
+		ProgressBar         ProgressBarConfigT  `toml:"progress_bar"`
+		Durations           DurationsConfigT    `toml:"durations"`
+		Cycle               CycleConfigT        `toml:"cycle"`
+		Sequence            []PhaseConfigT      `toml:"sequence"` // Replaces the cycle when it's not empty
 	}
 
 	ProgressBarConfigT struct  {
@@ -46,6 +161,19 @@ type (
 		ShortBreak   time.Duration   `toml:"short_break"`
 		LongBreak    time.Duration   `toml:"long_break"`
 	}
+
+	CycleConfigT struct {
+		SessionsBeforeLongBreak  uint8  `toml:"sessions_before_long_break"` // Focus sessions per long break
+	}
+
+	// A user defined phase, empty fields are taken from the built-in phase of the same kind
+	PhaseConfigT struct {
+		Name       string          `toml:"name"`
+		Kind       string          `toml:"kind"` // "focus", "short_break" or "long_break"
+		Duration   time.Duration   `toml:"duration"`
+		Color      string          `toml:"color"`
+		Msg        string          `toml:"msg"`
+	}
 )
 
 // TODO: handle the case of not finding the home dir or the config dir
@@ -60,32 +188,99 @@ var configPaths = []string{
 var defaultConfig = ConfigT {
 	TickDuration      :  time.Millisecond * 20,
 	MaxPauseDuration  :  time.Minute * 500,
-	Autostart         :  false,
 	Skipping          :  true,
 	Pausing           :  true,
 
-	ProgressBar: ProgressBarConfigT{
-		Padding   : 5,
-		MaxWidth  : 70,
+	Keys: KeysConfigT{
+		Toggle               : []string{"space"},
+		Reset                : []string{"ctrl+r"},
+		Skip                 : []string{"ctrl+s"},
+		Profiles             : []string{"p"},
+		Stats                : []string{"s"},
+		Tasks                : []string{"t"},
+		InternalInterruption : []string{"'"},
+		ExternalInterruption : []string{"-"},
+		Silence              : []string{"x"},
+		Snooze               : []string{"z"},
+		Mute                 : []string{"m"},
+		Help                 : []string{"?"},
+		Quit                 : []string{"q", "esc", "ctrl+c"},
+	},
+
+	Alarm: AlarmConfigT{
+		FocusEnd : "alarm",
+		BreakEnd : "alarm",
+		Volume   : 100,
+		Repeat   : 1,
+		Snooze   : 5 * time.Minute,
+	},
+
+	Ambient: AmbientConfigT{
+		Focus      : "none",
+		ShortBreak : "none",
+		LongBreak  : "none",
+		BPM        : 60,
+		Volume     : 30,
+	},
 
-		Border          : "normal",
-		FocusColor      : "1",
-		ShortBreakColor : "2",
-		LongBreakColor  : "6",
-		PauseColor      : "0",
+	Notifications: NotificationsConfigT{
+		Enabled      : true,
+		FocusUrgency : "critical",
+		BreakUrgency : "normal",
+	},
+
+	Hooks: HooksConfigT{
+		Timeout : 10 * time.Second,
+	},
 
-		FocusMsg        : "Let's Focus",
-		ShortBreakMsg   : "Short break",
-		LongBreakMsg    : "You deserve it",
-		PauseMsg        : "Get back to focusing",
+	HTTP: HTTPConfigT{
+		Enabled : false,
+		Listen  : "127.0.0.1:7878",
 	},
 
-	Durations: DurationsConfigT{
-		Focus       : 25 * time.Minute,
-		ShortBreak  : 5 * time.Minute,
-		LongBreak   : 20 * time.Minute,
+	Tasks: TasksConfigT{
+		Source      : "plumadoro",
+		TodoTxt     : "~/todo.txt",
+		Taskwarrior : "task",
 	},
 
+	Log: LogConfigT{
+		Rotation : "monthly",
+		MaxSize  : 1024,
+	},
+
+	ProfileConfigT: ProfileConfigT{
+		Autostart: false,
+
+		ProgressBar: ProgressBarConfigT{
+			Padding   : 5,
+			MaxWidth  : 70,
+
+			Border          : "normal",
+			FocusColor      : "1",
+			ShortBreakColor : "2",
+			LongBreakColor  : "6",
+			PauseColor      : "0",
+
+			FocusMsg        : "Let's Focus",
+			ShortBreakMsg   : "Short break",
+			LongBreakMsg    : "You deserve it",
+			PauseMsg        : "Get back to focusing",
+		},
+
+		Durations: DurationsConfigT{
+			Focus       : 25 * time.Minute,
+			ShortBreak  : 5 * time.Minute,
+			LongBreak   : 20 * time.Minute,
+		},
+
+		Cycle: CycleConfigT{
+			SessionsBeforeLongBreak: 4,
+		},
+	},
+
+	Profile: DefaultProfile,
+
 	loadedConfigPath : "",
 	loadedConfig:      false,
 }
@@ -96,8 +291,12 @@ var (
 	ErrFailedParsingTOML   = errors.New("Failed parsing the TOML configuration file")
 	ErrUnsupportedKeys     = errors.New("Unsupported keys in the TOML config")
 	ErrInvalidKeyValue     = errors.New("Invalid value for TOML key/s") // it parsed well but the value is wrong
+	ErrUnknownProfile      = errors.New("Unknown profile")
 )
 
+// The name of the profile made of the top level options
+const DefaultProfile string = "default"
+
 var Config ConfigT = defaultConfig
 
 
@@ -122,6 +321,7 @@ func validateOption(errsPtr *[]error, valuePtr *string, optionsPtr *[]string, de
 		}
 	}
 
+	*valuePtr = defaultValue
 	*errsPtr = append(*errsPtr, fmt.Errorf("Invalid %s: must be from the following:\n%#v", key, *optionsPtr))
 }
 
@@ -168,12 +368,172 @@ func validateColor(errsPtr *[]error, valuePtr *string, defaultValue string, key
 	*errsPtr = append(*errsPtr, fmt.Errorf("Invalid %s: must be an ANSI color or HEX color as a string", key))
 }
 
+// Validates the options of the active profile, prefix is prepended to the keys in the errors
+func validateProfile(prefix string) []error {
+	var errs []error
+
+	// Autostart doesn't require validation
+
+	validateRange(&errs, &Config.ProgressBar.MaxWidth,
+		10, 150,
+		defaultConfig.ProgressBar.MaxWidth, prefix + "progress_bar.max_width")
+
+	validateRange(&errs, &Config.ProgressBar.Padding,
+		0, 50,
+		defaultConfig.ProgressBar.Padding, prefix + "progress_bar.padding")
+
+	validateOption(&errs, &Config.ProgressBar.Border,
+		&[]string{"rounded", "ascii", "thick", "double", "normal", "hidden"}, 
+		defaultConfig.ProgressBar.Border, prefix + "progress_bar.border_type")
+
+	validateColor(&errs, &Config.ProgressBar.FocusColor,
+		defaultConfig.ProgressBar.FocusColor, prefix + "progress_bar.focus_color")
+
+	validateColor(&errs, &Config.ProgressBar.ShortBreakColor,
+		defaultConfig.ProgressBar.ShortBreakColor, prefix + "progress_bar.short_break_color")
+
+	validateColor(&errs, &Config.ProgressBar.LongBreakColor,
+		defaultConfig.ProgressBar.LongBreakColor, prefix + "progress_bar.long_break_color")
+
+	validateColor(&errs, &Config.ProgressBar.PauseColor,
+		defaultConfig.ProgressBar.LongBreakColor, prefix + "progress_bar.pause_color")
+
+	validateStringLen(&errs, &Config.ProgressBar.FocusMsg,
+		0, 1028,
+		defaultConfig.ProgressBar.FocusMsg, prefix + "progress_bar.focus_msg")
+
+	validateStringLen(&errs, &Config.ProgressBar.ShortBreakMsg,
+		0, 1028,
+		defaultConfig.ProgressBar.ShortBreakMsg, prefix + "progress_bar.short_break_msg")
+
+	validateStringLen(&errs, &Config.ProgressBar.LongBreakMsg,
+		0, 1028,
+		defaultConfig.ProgressBar.LongBreakMsg, prefix + "progress_bar.long_break_msg")
+
+	validateStringLen(&errs, &Config.ProgressBar.PauseMsg,
+		0, 1028,
+		defaultConfig.ProgressBar.LongBreakMsg, prefix + "progress_bar.pause_msg")
+	
+	validateRange(&errs, &Config.Durations.Focus,
+		time.Second*1, time.Minute*1000,
+		defaultConfig.Durations.Focus, prefix + "duration.focus")
+
+	validateRange(&errs, &Config.Durations.ShortBreak,
+		time.Second*1, time.Minute*1000,
+		defaultConfig.Durations.ShortBreak, prefix + "duration.short_break")
+
+	validateRange(&errs, &Config.Durations.LongBreak,
+		time.Second*1, time.Minute*1000,
+		defaultConfig.Durations.LongBreak, prefix + "duration.long_break")
+
+	validateRange(&errs, &Config.Cycle.SessionsBeforeLongBreak,
+		1, 50,
+		defaultConfig.Cycle.SessionsBeforeLongBreak, prefix + "cycle.sessions_before_long_break")
+
+	if len(Config.Sequence) > 100 {
+		Config.Sequence = Config.Sequence[:100]
+		errs = append(errs, fmt.Errorf("Invalid %ssequence: it can't have more than 100 phases", prefix))
+	}
+
+	for i := range Config.Sequence {
+		c   := &Config.Sequence[i]
+		key := fmt.Sprintf("%ssequence[%d]", prefix, i)
+
+		// The kind can be left out when the name is one of the built-in phases, otherwise a break
+		// would silently count as focus time
+		if c.Kind == "" {
+			if _, ok := parsePhaseType(c.Name); ok {
+				c.Kind = c.Name
+			} else {
+				c.Kind = "focus"
+				errs = append(errs, fmt.Errorf("Invalid %s.kind: it's required when the name isn't one of %q",
+					key, phaseTypeNames))
+			}
+		}
+
+		validateOption(&errs, &c.Kind,
+			&phaseTypeNames,
+			"focus", key + ".kind")
+
+		kind, _ := parsePhaseType(c.Kind)
+		builtin := defaultPhase(kind)
+
+		validateStringLen(&errs, &c.Name,
+			1, 64,
+			builtin.name, key + ".name")
+
+		if c.Duration == 0 {
+			c.Duration = builtin.duration
+		}
+
+		validateRange(&errs, &c.Duration,
+			time.Second*1, time.Minute*1000,
+			builtin.duration, key + ".duration")
+
+		if c.Color != "" {
+			validateColor(&errs, &c.Color,
+				builtin.color, key + ".color")
+		}
+
+		validateStringLen(&errs, &c.Msg,
+			0, 1028,
+			builtin.msg, key + ".msg")
+	}
+
+	return errs
+}
+
+// Makes the profile with the given name the active one
+func UseProfile(name string) error {
+	profile, ok := Config.profiles[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownProfile, name)
+	}
+
+	Config.ProfileConfigT = profile
+	Config.Profile        = name
+	Config.overrides.apply(&Config.ProfileConfigT)
+
+	return nil
+}
+
+func (o *ProfileOverridesT) apply(profile *ProfileConfigT) {
+	if o.Focus != nil {
+		profile.Durations.Focus = *o.Focus
+	}
+	if o.ShortBreak != nil {
+		profile.Durations.ShortBreak = *o.ShortBreak
+	}
+	if o.LongBreak != nil {
+		profile.Durations.LongBreak = *o.LongBreak
+	}
+	if o.Autostart != nil {
+		profile.Autostart = *o.Autostart
+	}
+}
+
+// The names of the available profiles sorted with the default one first
+func ProfileNames() []string {
+	names := []string{DefaultProfile}
+	for name := range Config.profiles {
+		if name != DefaultProfile {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names[1:])
+
+	return names
+}
+
 func LoadConfig() error {
 	var errs []error // error messages to be concated
 
 	if Config.loadedConfig {
 		return ErrConfigAlreadyLoaded
 	}
+
+	// So the default profile is usable even when there is no config file
+	Config.profiles = map[string]ProfileConfigT{DefaultProfile: Config.ProfileConfigT}
 	
 	// Loading the config file data
 	var dat []byte
@@ -192,24 +552,16 @@ func LoadConfig() error {
 		}
 	}
 
-	// Parsing the TOML config
-	{
-		md, err := toml.Decode(string(dat), &Config)
-		if err != nil {
-			return errors.Join(ErrFailedParsingTOML, err)
-		}
-
-		if undecoded := md.Undecoded(); len(undecoded) != 0 {
-			errs = append(errs, fmt.Errorf("%w: Unsupported keys: %q", ErrUnsupportedKeys, undecoded))
-		}
+	// Parsing the TOML config (the unsupported keys are checked after decoding the profiles)
+	md, err := toml.Decode(string(dat), &Config)
+	if err != nil {
+		return errors.Join(ErrFailedParsingTOML, err)
 	}
 
 	validateRange(&errs, &Config.TickDuration,
 		time.Microsecond, time.Second * 5,
 		defaultConfig.TickDuration, "tick_duration")
 
-	// Autostart doesn't require validation
-
 	// Skipping doesn't require validation
 
 	// Pausing doesn't require validation too
@@ -218,59 +570,135 @@ func LoadConfig() error {
 		time.Minute*0, time.Minute*1000,
 		defaultConfig.MaxPauseDuration, "max_pause_duration")
 
-	validateRange(&errs, &Config.ProgressBar.MaxWidth,
-		10, 150,
-		defaultConfig.ProgressBar.MaxWidth, "progress_bar.max_width")
+	validateOption(&errs, &Config.Log.Rotation,
+		&[]string{"monthly", "size", "none"},
+		defaultConfig.Log.Rotation, "log.rotation")
 
-	validateRange(&errs, &Config.ProgressBar.Padding,
-		0, 50,
-		defaultConfig.ProgressBar.Padding, "progress_bar.padding")
+	validateRange(&errs, &Config.Log.MaxSize,
+		16, 1024*1024,
+		defaultConfig.Log.MaxSize, "log.max_size")
 
-	validateOption(&errs, &Config.ProgressBar.Border,
-		&[]string{"rounded", "ascii", "thick", "double", "normal", "hidden"}, 
-		defaultConfig.ProgressBar.Border, "progress_bar.border_type")
+	validateRange(&errs, &Config.Goals.DailyPomodoros,
+		0, 100,
+		defaultConfig.Goals.DailyPomodoros, "goals.daily_pomodoros")
 
-	validateColor(&errs, &Config.ProgressBar.FocusColor,
-		defaultConfig.ProgressBar.FocusColor, "progress_bar.focus_color")
+	validateRange(&errs, &Config.Goals.DailyFocus,
+		0, time.Hour*24,
+		defaultConfig.Goals.DailyFocus, "goals.daily_focus")
 
-	validateColor(&errs, &Config.ProgressBar.ShortBreakColor,
-		defaultConfig.ProgressBar.ShortBreakColor, "progress_bar.short_break_color")
+	validateRange(&errs, &Config.Goals.WeeklyPomodoros,
+		0, 700,
+		defaultConfig.Goals.WeeklyPomodoros, "goals.weekly_pomodoros")
 
-	validateColor(&errs, &Config.ProgressBar.LongBreakColor,
-		defaultConfig.ProgressBar.LongBreakColor, "progress_bar.long_break_color")
+	validateRange(&errs, &Config.Goals.WeeklyFocus,
+		0, time.Hour*24*7,
+		defaultConfig.Goals.WeeklyFocus, "goals.weekly_focus")
 
-	validateColor(&errs, &Config.ProgressBar.PauseColor,
-		defaultConfig.ProgressBar.LongBreakColor, "progress_bar.pause_color")
+	validateOption(&errs, &Config.Tasks.Source,
+		&[]string{"plumadoro", "todotxt", "taskwarrior"},
+		defaultConfig.Tasks.Source, "tasks.source")
 
-	validateStringLen(&errs, &Config.ProgressBar.FocusMsg,
-		0, 1028,
-		defaultConfig.ProgressBar.FocusMsg, "progress_bar.focus_msg")
+	validateStringLen(&errs, &Config.Tasks.TodoTxt,
+		1, 4096,
+		defaultConfig.Tasks.TodoTxt, "tasks.todo_txt")
 
-	validateStringLen(&errs, &Config.ProgressBar.ShortBreakMsg,
-		0, 1028,
-		defaultConfig.ProgressBar.ShortBreakMsg, "progress_bar.short_break_msg")
+	validateStringLen(&errs, &Config.Tasks.Taskwarrior,
+		1, 4096,
+		defaultConfig.Tasks.Taskwarrior, "tasks.taskwarrior")
 
-	validateStringLen(&errs, &Config.ProgressBar.LongBreakMsg,
-		0, 1028,
-		defaultConfig.ProgressBar.LongBreakMsg, "progress_bar.long_break_msg")
+	validateRange(&errs, &Config.Hooks.Timeout,
+		time.Millisecond*100, time.Minute*10,
+		defaultConfig.Hooks.Timeout, "hooks.timeout")
 
-	validateStringLen(&errs, &Config.ProgressBar.PauseMsg,
-		0, 1028,
-		defaultConfig.ProgressBar.LongBreakMsg, "progress_bar.pause_msg")
-	
-	validateRange(&errs, &Config.Durations.Focus,
-		time.Second*1, time.Minute*1000,
-		defaultConfig.Durations.Focus, "duration.focus")
+	validateListen(&errs, &Config.HTTP.Listen,
+		defaultConfig.HTTP.Listen)
 
-	validateRange(&errs, &Config.Durations.ShortBreak,
-		time.Second*1, time.Minute*1000,
-		defaultConfig.Durations.ShortBreak, "duration.short_break")
+	validateWebhooks(&errs)
 
-	validateRange(&errs, &Config.Durations.LongBreak,
-		time.Second*1, time.Minute*1000,
-		defaultConfig.Durations.LongBreak, "duration.long_break")
+	validateKeys(&errs)
+
+	validateSound(&errs, &Config.Alarm.FocusEnd,
+		defaultConfig.Alarm.FocusEnd, "alarm.focus_end")
+
+	validateSound(&errs, &Config.Alarm.BreakEnd,
+		defaultConfig.Alarm.BreakEnd, "alarm.break_end")
+
+	validateRange(&errs, &Config.Alarm.Volume,
+		0, 100,
+		defaultConfig.Alarm.Volume, "alarm.volume")
+
+	validateRange(&errs, &Config.Alarm.Repeat,
+		1, 10,
+		defaultConfig.Alarm.Repeat, "alarm.repeat")
+
+	validateRange(&errs, &Config.Alarm.Snooze,
+		time.Second*1, time.Hour*1,
+		defaultConfig.Alarm.Snooze, "alarm.snooze")
+
+	validateOption(&errs, &Config.Ambient.Focus,
+		&ambientSounds,
+		defaultConfig.Ambient.Focus, "ambient.focus")
+
+	validateOption(&errs, &Config.Ambient.ShortBreak,
+		&ambientSounds,
+		defaultConfig.Ambient.ShortBreak, "ambient.short_break")
+
+	validateOption(&errs, &Config.Ambient.LongBreak,
+		&ambientSounds,
+		defaultConfig.Ambient.LongBreak, "ambient.long_break")
+
+	validateRange(&errs, &Config.Ambient.BPM,
+		20, 300,
+		defaultConfig.Ambient.BPM, "ambient.bpm")
+
+	validateRange(&errs, &Config.Ambient.Volume,
+		0, 100,
+		defaultConfig.Ambient.Volume, "ambient.volume")
+
+	validateOption(&errs, &Config.Notifications.FocusUrgency,
+		&[]string{"low", "normal", "critical"},
+		defaultConfig.Notifications.FocusUrgency, "notifications.focus_urgency")
+
+	validateOption(&errs, &Config.Notifications.BreakUrgency,
+		&[]string{"low", "normal", "critical"},
+		defaultConfig.Notifications.BreakUrgency, "notifications.break_urgency")
+
+	// Validating the default profile then decoding the other profiles over a copy of it
+	Config.profiles = map[string]ProfileConfigT{}
+
+	errs = append(errs, validateProfile("")...)
+	base := Config.ProfileConfigT
+	Config.profiles[DefaultProfile] = base
+
+	for name, primitive := range Config.Profiles {
+		if name == DefaultProfile {
+			errs = append(errs, fmt.Errorf("Invalid profiles.%s: it's the name of the top level options", name))
+			continue
+		}
+
+		Config.ProfileConfigT = base
+		Config.Sequence = slices.Clone(base.Sequence)
+
+		if err := md.PrimitiveDecode(primitive, &Config.ProfileConfigT); err != nil {
+			errs = append(errs, fmt.Errorf("%w: profiles.%s: %w", ErrFailedParsingTOML, name, err))
+			continue
+		}
+
+		errs = append(errs, validateProfile(fmt.Sprintf("profiles.%s.", name))...)
+		Config.profiles[name] = Config.ProfileConfigT
+	}
+	Config.ProfileConfigT = base
+
+	if undecoded := md.Undecoded(); len(undecoded) != 0 {
+		errs = append(errs, fmt.Errorf("%w: Unsupported keys: %q", ErrUnsupportedKeys, undecoded))
+	}
+
+	if err := UseProfile(Config.Profile); err != nil {
+		errs = append(errs, fmt.Errorf("Invalid profile: %w", err))
+		Config.Profile = DefaultProfile
+	}
 
-	err := errors.Join(errs...)
+	err = errors.Join(errs...)
 
 	if err != nil {
 		err = fmt.Errorf("%w\n%w", err, ErrInvalidKeyValue)
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Replaces the config for the test and puts the default one back after it
+func useConfig(t *testing.T, config ConfigT) {
+	t.Helper()
+
+	Config = config
+	t.Cleanup(func() { Config = defaultConfig })
+}
+
+func TestSequenceKind(t *testing.T) {
+	tests := []struct {
+		name  string
+		phase PhaseConfigT
+		kind  string
+		err   bool
+	}{
+		{"built-in name", PhaseConfigT{Name: "short_break"}, "short_break", false},
+		{"explicit kind", PhaseConfigT{Name: "lunch", Kind: "long_break"}, "long_break", false},
+		{"missing kind", PhaseConfigT{Name: "lunch", Duration: time.Hour}, "focus", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := defaultConfig
+			config.Sequence = []PhaseConfigT{test.phase}
+			useConfig(t, config)
+
+			errs := validateProfile("")
+
+			if got := Config.Sequence[0].Kind; got != test.kind {
+				t.Errorf("kind = %q, want %q", got, test.kind)
+			}
+			if failed := len(errs) != 0; failed != test.err {
+				t.Fatalf("errors = %v, want an error: %v", errs, test.err)
+			}
+			if test.err && !strings.Contains(errs[0].Error(), "sequence[0].kind") {
+				t.Errorf("error = %q, want it to name sequence[0].kind", errs[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The daemon owns the PomodoroModel and the clients (the TUI included) control it over a unix
+// socket, every request and response is a JSON object in a single line:
+//
+//	-> {"cmd": "toggle"}
+//	<- {"ok": true, "state": {...}}
+//
+// The commands are start [phase], pause, toggle, skip, reset, profile <name>, task <id>,
+// interrupt <internal|external> [note], silence, snooze, mute, status and subscribe,
+// after subscribing the daemon keeps sending {"ok": true, "event": "...", "state": {...}}
+// on every change until the connection is closed.
+
+type daemonRequest struct {
+	Cmd      string    `json:"cmd"`
+	Args     []string  `json:"args,omitempty"`
+}
+
+type daemonResponse struct {
+	Ok       bool            `json:"ok"`
+	Error    string          `json:"error,omitempty"`
+	Event    string          `json:"event,omitempty"` // only in the responses sent to subscribers
+	State    *PomodoroState  `json:"state,omitempty"`
+}
+
+// A request waiting for the daemon's loop to handle it
+type daemonCall struct {
+	request  daemonRequest
+	reply    chan daemonResponse
+	events   chan daemonResponse // the channel to (un)subscribe
+}
+
+type daemon struct {
+	model        *PomodoroModel
+	calls        chan daemonCall
+	subscribers  map[chan daemonResponse]bool
+	api          *apiServer // nil when the HTTP API is disabled
+}
+
+// Sent to the TUI when the daemon it's attached to sends a new state
+type DaemonStateMsg struct {
+	State       PomodoroState
+	Subscribed  bool // it came from the subscription rather than a command's response
+}
+
+// Sent to the TUI when the connection to the daemon is lost
+type DaemonLostMsg struct{}
+
+var (
+	ErrDaemonRunning     = errors.New("The daemon is already running")
+	ErrDaemonNotRunning  = errors.New("The daemon isn't running")
+	ErrDaemonProtocol    = errors.New("Invalid message from the daemon")
+)
+
+const unsubscribe string = "unsubscribe" // internal command sent when a subscriber disconnects
+
+func socketPath() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		return fmt.Sprintf("%s/plumadoro-%d.sock", os.TempDir(), os.Getuid())
+	}
+
+	return fmt.Sprintf("%s/plumadoro.sock", runtimeDir)
+}
+
+func runDaemon(args []string) error {
+	_, configErr, err := setup("daemon", args, nil)
+	if err != nil {
+		return err
+	}
+
+	if configErr != nil {
+		fmt.Fprintln(os.Stderr, configErr)
+	}
+
+	path := socketPath()
+
+	// A socket nobody is listening to is left from a daemon that didn't exit cleanly
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%w: %s", ErrDaemonRunning, path)
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	defer listener.Close()
+
+	d := &daemon{
+		model:       NewPomodoroModel(SystemClock),
+		calls:       make(chan daemonCall),
+		subscribers: map[chan daemonResponse]bool{},
+	}
+
+	if err := d.model.load(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	if Config.HTTP.Enabled {
+		d.api = newAPIServer(func(ctx context.Context, request daemonRequest) (daemonResponse, error) {
+			return d.call(request, nil), nil
+		})
+		if err := d.api.listen(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		defer d.api.close()
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+
+	return d.loop()
+}
+
+// The only goroutine touching the model, the connections send their requests to it
+func (d *daemon) loop() error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	ticker := time.NewTicker(Config.TickDuration)
+	defer ticker.Stop()
+
+	d.model.lastTick = d.model.clock.Now()
+
+	// The clicks on the buttons of the notifications, nil (never ready) when they're disabled
+	var actions chan string
+	if Config.Notifications.Enabled {
+		actions = getNotifier().actions
+	}
+
+	// The failures of the webhooks, nil when there are none
+	var warnings chan string
+	if len(Config.Webhooks) != 0 {
+		warnings = getOutbox().warnings
+	}
+
+	for {
+		select {
+		case call := <-d.calls:
+			call.reply <- d.handle(call)
+			d.flush()
+
+		case <-ticker.C:
+			n := d.model.n
+			now := d.model.clock.Now()
+
+			// Nobody is there to answer how a suspend gap is counted, so it stays paused time
+			if gap := d.model.tick(now); gap != 0 {
+				d.broadcast("suspend_gap")
+			}
+
+			if d.model.pauseLimitReached() {
+				d.model.reset()
+				d.model.emit(ResetEvent)
+				d.broadcast("reset")
+			}
+
+			if d.model.n != n {
+				d.broadcast("next")
+			}
+			d.flush()
+
+		case action := <-actions:
+			if err := d.model.control(action, nil); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			} else {
+				d.broadcast(action)
+			}
+			d.flush()
+
+		case warning := <-warnings:
+			fmt.Fprintln(os.Stderr, warning)
+
+		case failure := <-hooks.failures:
+			fmt.Fprintln(os.Stderr, failure)
+
+		case <-signals:
+			d.model.emit(AppQuitEvent)
+			hooks.run(d.model.hooks(d.model.pending))
+			err := d.model.flush()
+			hooks.wait()
+
+			return err
+		}
+	}
+}
+
+func (d *daemon) flush() {
+	events := d.model.pending
+	runs := d.model.hooks(events)
+	if err := d.model.flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	d.model.queueWebhooks(events)
+
+	if d.api != nil && len(events) != 0 {
+		d.api.publish(events, d.model.eventState)
+	}
+
+	hooks.run(runs)
+}
+
+func (d *daemon) handle(call daemonCall) daemonResponse {
+	switch (call.request.Cmd) {
+	case "subscribe":
+		d.subscribers[call.events] = true
+
+	case unsubscribe:
+		if d.subscribers[call.events] {
+			delete(d.subscribers, call.events)
+			close(call.events)
+		}
+		return daemonResponse{Ok: true}
+
+	default:
+		if err := d.model.control(call.request.Cmd, call.request.Args); err != nil {
+			return daemonResponse{Ok: false, Error: err.Error()}
+		}
+
+		if call.request.Cmd != "status" {
+			d.broadcast(call.request.Cmd)
+		}
+	}
+
+	state := d.model.state()
+	return daemonResponse{Ok: true, State: &state}
+}
+
+func (d *daemon) broadcast(event string) {
+	state := d.model.state()
+
+	for events := range d.subscribers {
+		select {
+		case events <- daemonResponse{Ok: true, Event: event, State: &state}:
+		default:
+			// Too slow to keep up, it has to subscribe again
+			delete(d.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+func (d *daemon) call(request daemonRequest, events chan daemonResponse) daemonResponse {
+	reply := make(chan daemonResponse)
+	d.calls <- daemonCall{request: request, reply: reply, events: events}
+
+	return <-reply
+}
+
+func (d *daemon) serve(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		var request daemonRequest
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			encoder.Encode(daemonResponse{Ok: false, Error: err.Error()})
+			continue
+		}
+
+		if request.Cmd == unsubscribe {
+			continue
+		}
+
+		if request.Cmd != "subscribe" {
+			if encoder.Encode(d.call(request, nil)) != nil {
+				return
+			}
+			continue
+		}
+
+		events := make(chan daemonResponse, 64)
+		if encoder.Encode(d.call(request, events)) != nil {
+			d.call(daemonRequest{Cmd: unsubscribe}, events)
+			return
+		}
+
+		// Reading until the client hangs up, a subscribed connection doesn't take other commands
+		go func() {
+			for scanner.Scan() {}
+			d.call(daemonRequest{Cmd: unsubscribe}, events)
+		}()
+
+		for response := range events {
+			if encoder.Encode(response) != nil {
+				d.call(daemonRequest{Cmd: unsubscribe}, events)
+				for range events {}
+				return
+			}
+		}
+		return
+	}
+}
+
+
+// The TUI's side of the connection, it's nil when no daemon is running
+type daemonClient struct {
+	path    string
+	events  chan daemonResponse
+}
+
+func callDaemon(path string, request daemonRequest) (daemonResponse, error) {
+	var response daemonResponse
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return response, fmt.Errorf("%w: %w", ErrDaemonNotRunning, err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return response, err
+	}
+
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return response, fmt.Errorf("%w: %w", ErrDaemonProtocol, err)
+	}
+
+	return response, nil
+}
+
+// Subscribes to the daemon at the socket path, it fails if there is no daemon
+func attachDaemon() (*daemonClient, error) {
+	path := socketPath()
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDaemonNotRunning, err)
+	}
+
+	if err := json.NewEncoder(conn).Encode(daemonRequest{Cmd: "subscribe"}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := &daemonClient{path: path, events: make(chan daemonResponse, 64)}
+
+	go func() {
+		defer conn.Close()
+		defer close(c.events)
+
+		decoder := json.NewDecoder(conn)
+		for {
+			var response daemonResponse
+			if decoder.Decode(&response) != nil {
+				return
+			}
+			c.events <- response
+		}
+	}()
+
+	return c, nil
+}
+
+func (c *daemonClient) command(cmd string, args ...string) tea.Cmd {
+	return func() tea.Msg {
+		response, err := callDaemon(c.path, daemonRequest{Cmd: cmd, Args: args})
+		if err != nil {
+			return PopupMsg{Type: ErrorPopup, Content: err.Error()}
+		}
+
+		if !response.Ok || response.State == nil {
+			return PopupMsg{Type: WarningPopup, Content: response.Error}
+		}
+
+		return DaemonStateMsg{State: *response.State}
+	}
+}
+
+// Waits for the next state the daemon sends
+func (c *daemonClient) wait() tea.Cmd {
+	return func() tea.Msg {
+		for response := range c.events {
+			if response.State != nil {
+				return DaemonStateMsg{State: *response.State, Subscribed: true}
+			}
+		}
+
+		return DaemonLostMsg{}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/bubbles/progress"
+)
+
+// The progress towards the daily & weekly goals, the days before today are read from the whole log
+// once and today is read again from the tail of the log whenever new events are written
+type goalsTracker struct {
+	clock        Clock
+	day          string // the date the totals below are for
+	today        statsPeriod
+	week         statsPeriod // the days of this week before today
+	streak       int // consecutive days before today meeting the daily goal
+	celebrated   map[string]bool // the goals a popup was shown for, by "daily"/"weekly" + date
+	progressBar  progress.Model
+}
+
+func goalsEnabled() bool {
+	g := Config.Goals
+	return g.DailyPomodoros != 0 || g.DailyFocus != 0 || g.WeeklyPomodoros != 0 || g.WeeklyFocus != 0
+}
+
+func dailyGoalEnabled() bool {
+	return Config.Goals.DailyPomodoros != 0 || Config.Goals.DailyFocus != 0
+}
+
+func weeklyGoalEnabled() bool {
+	return Config.Goals.WeeklyPomodoros != 0 || Config.Goals.WeeklyFocus != 0
+}
+
+// How much of a goal is done from 0 to 1, every target that is set has to be reached
+func goalProgress(s statsPeriod, pomodoros uint16, focus time.Duration) float64 {
+	done := 1.0
+
+	if pomodoros != 0 {
+		done = min(done, float64(s.pomodoros) / float64(pomodoros))
+	}
+	if focus != 0 {
+		done = min(done, float64(s.focus) / float64(focus))
+	}
+
+	return done
+}
+
+func dailyGoalMet(s statsPeriod) bool {
+	return dailyGoalEnabled() && goalProgress(s, Config.Goals.DailyPomodoros, Config.Goals.DailyFocus) >= 1
+}
+
+func weeklyGoalMet(s statsPeriod) bool {
+	return weeklyGoalEnabled() && goalProgress(s, Config.Goals.WeeklyPomodoros, Config.Goals.WeeklyFocus) >= 1
+}
+
+func newGoalsTracker(clock Clock) *goalsTracker {
+	return &goalsTracker{
+		clock:       clock,
+		celebrated:  map[string]bool{},
+		progressBar: progress.New(
+			progress.WithSolidFill(Config.ProgressBar.FocusColor),
+			progress.WithoutPercentage(),
+		),
+	}
+}
+
+// Reads the days before today from the log, the goals already met aren't celebrated again
+func (g *goalsTracker) load() error {
+	now := g.clock.Now()
+	g.day    = now.Format(time.DateOnly)
+	g.week   = statsPeriod{}
+	g.streak = 0
+
+	events, err := readAllEvents()
+	if err != nil {
+		return err
+	}
+
+	days := map[string]statsPeriod{}
+	for _, s := range summarize(events, statsGroupings["day"], time.Time{}, time.Time{}) {
+		days[s.period] = *s
+	}
+
+	today := startOfDay(now)
+	for d := startOfWeek(today); d.Before(today); d = d.AddDate(0, 0, 1) {
+		s := days[d.Format(time.DateOnly)]
+		g.week.pomodoros += s.pomodoros
+		g.week.focus     += s.focus
+	}
+
+	for d := today.AddDate(0, 0, -1); dailyGoalMet(days[d.Format(time.DateOnly)]); d = d.AddDate(0, 0, -1) {
+		g.streak++
+	}
+
+	g.today = days[g.day]
+	g.celebrated["daily" + g.day]  = dailyGoalMet(g.today)
+	g.celebrated["weekly" + g.day] = weeklyGoalMet(g.thisWeek())
+
+	return nil
+}
+
+func (g *goalsTracker) thisWeek() statsPeriod {
+	week := g.week
+	week.pomodoros += g.today.pomodoros
+	week.focus     += g.today.focus
+
+	return week
+}
+
+// Reads today's events again, it returns a popup when a goal was just reached
+func (g *goalsTracker) refresh() tea.Cmd {
+	now := g.clock.Now()
+
+	// The past days are read again when the day changes
+	if now.Format(time.DateOnly) != g.day {
+		if err := g.load(); err != nil {
+			return nil
+		}
+	}
+
+	events, err := readTailEvents(func(e pomodoroEvent) bool {
+		return sameDay(e.time_, now)
+	})
+	if err != nil {
+		return nil
+	}
+
+	g.today = statsPeriod{}
+	if periods := summarize(events, statsGroupings["day"], time.Time{}, time.Time{}); len(periods) != 0 {
+		g.today = *periods[len(periods) - 1]
+	}
+
+	var reached []string
+	if dailyGoalMet(g.today) && !g.celebrated["daily" + g.day] {
+		g.celebrated["daily" + g.day] = true
+		reached = append(reached, fmt.Sprintf("You reached your daily goal, that's %s in a row!",
+			plural(g.streak + 1, "day")))
+	}
+	if weeklyGoalMet(g.thisWeek()) && !g.celebrated["weekly" + g.day] {
+		g.celebrated["weekly" + g.day] = true
+		reached = append(reached, "You reached your weekly goal!")
+	}
+
+	if len(reached) == 0 {
+		return nil
+	}
+
+	content := strings.Join(reached, "\n")
+	return func() tea.Msg { return PopupMsg{Type: AlarmPopup, Content: content} }
+}
+
+// The streak including today if its goal is already met
+func (g *goalsTracker) currentStreak() int {
+	if dailyGoalMet(g.today) {
+		return g.streak + 1
+	}
+
+	return g.streak
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// The done and target of a goal like "3/8 pomodoros, 1h15m/4h00m"
+func goalLabel(s statsPeriod, pomodoros uint16, focus time.Duration) string {
+	var parts []string
+
+	if pomodoros != 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d pomodoros", s.pomodoros, pomodoros))
+	}
+	if focus != 0 {
+		parts = append(parts, fmt.Sprintf("%s/%s", hhmm(s.focus), hhmm(focus)))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func (g *goalsTracker) Render(width int) string {
+	g.progressBar.Width = width
+
+	var lines []string
+
+	if dailyGoalEnabled() {
+		lines = append(lines,
+			g.progressBar.ViewAs(goalProgress(g.today, Config.Goals.DailyPomodoros, Config.Goals.DailyFocus)),
+			fmt.Sprintf("Today: %s | streak: %s",
+				goalLabel(g.today, Config.Goals.DailyPomodoros, Config.Goals.DailyFocus),
+				plural(g.currentStreak(), "day")),
+		)
+	}
+
+	if weeklyGoalEnabled() {
+		week := g.thisWeek()
+		lines = append(lines,
+			g.progressBar.ViewAs(goalProgress(week, Config.Goals.WeeklyPomodoros, Config.Goals.WeeklyFocus)),
+			"This week: " + goalLabel(week, Config.Goals.WeeklyPomodoros, Config.Goals.WeeklyFocus),
+		)
+	}
+
+	return strings.Join(lines, "\n")
+}
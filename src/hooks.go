@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The hooks are the user's commands run by sh on the events of the timer, they get the event as
+// PLUMADORO_* environment variables and as a JSON object on stdin. They run one after the other in
+// the order of their events, outside of the loop of the TUI or the daemon, and are killed after the
+// timeout
+
+// What a hook gets on stdin
+type hookPayload struct {
+	Event      string     `json:"event"`
+	Time       time.Time  `json:"time"`
+	Phase      string     `json:"phase"`
+	Kind       string     `json:"kind"`
+	N          uint64     `json:"n"`
+	Cycle      int        `json:"cycle"`     // one based index of the loop over the phases
+	Session    int        `json:"session"`   // one based index of the focus session
+	Duration   float64    `json:"duration"`  // seconds
+	Remaining  float64    `json:"remaining"` // seconds
+	Paused     float64    `json:"paused"`    // seconds
+	Running    bool       `json:"running"`
+	Profile    string     `json:"profile"`
+	Task       string     `json:"task"`
+	TaskTitle  string     `json:"task_title"`
+	Tags       []string   `json:"tags"`
+}
+
+// A hook to run for an event
+type hookRun struct {
+	name     string // the key of the config
+	command  string
+	payload  hookPayload
+}
+
+// Runs the queued hooks one after the other in the background
+type hookRunner struct {
+	mu        sync.Mutex
+	queue     []hookRun
+	running   bool // a goroutine is running the queue
+	idle      *sync.Cond // signaled once the queue is empty
+	failures  chan string // the failures to show
+}
+
+// Sent to the TUI when a hook failed
+type HookFailedMsg struct {
+	Content string
+}
+
+var ErrHookFailed = errors.New("Hook failed")
+
+var hooks = newHookRunner()
+
+func newHookRunner() *hookRunner {
+	r := &hookRunner{failures: make(chan string, 16)}
+	r.idle = sync.NewCond(&r.mu)
+
+	return r
+}
+
+// The config key of the hook of an event, empty when the event has none
+func hookName(e pomodoroEvent) string {
+	switch (e.event) {
+	case PhaseStartedEvent:
+		if e.kind == Focus {
+			return "on_focus_start"
+		}
+		return "on_break_start"
+	case PausedEvent:
+		return "on_pause"
+	case ResumedEvent:
+		return "on_resume"
+	case SkippedEvent:
+		return "on_skip"
+	case ResetEvent:
+		return "on_reset"
+	case AppQuitEvent:
+		return "on_quit"
+	}
+
+	return ""
+}
+
+func hookCommand(name string) string {
+	h := &Config.Hooks
+
+	switch (name) {
+	case "on_focus_start": return h.OnFocusStart
+	case "on_break_start": return h.OnBreakStart
+	case "on_pause":       return h.OnPause
+	case "on_resume":      return h.OnResume
+	case "on_skip":        return h.OnSkip
+	case "on_reset":       return h.OnReset
+	case "on_quit":        return h.OnQuit
+	}
+
+	return ""
+}
+
+// The hooks of the given events in their order
+func (m *PomodoroModel) hooks(events []pomodoroEvent) []hookRun {
+	var runs []hookRun
+
+	for _, e := range events {
+		name := hookName(e)
+		command := hookCommand(name)
+		if command == "" {
+			continue
+		}
+
+		runs = append(runs, hookRun{name: name, command: command, payload: m.payload(e)})
+	}
+
+	return runs
+}
+
+// The event as it's given to the hooks and the webhooks
+func (m *PomodoroModel) payload(e pomodoroEvent) hookPayload {
+	title := ""
+	if e.task == m.task.ID {
+		title = m.task.Title
+	}
+
+	return hookPayload{
+		Event:     e.event,
+		Time:      e.time_,
+		Phase:     e.phase,
+		Kind:      e.kind.String(),
+		N:         e.n,
+		Cycle:     int((e.n - 1) / uint64(len(m.phases))) + 1,
+		Session:   m.sessionOf(e.n),
+		Duration:  e.duration.Seconds(),
+		Remaining: e.remainingTime.Seconds(),
+		Paused:    e.pausedTime.Seconds(),
+		Running:   e.running,
+		Profile:   e.profile,
+		Task:      e.task,
+		TaskTitle: title,
+		Tags:      e.tags,
+	}
+}
+
+func (h hookRun) env() []string {
+	p := h.payload
+	seconds := func(s float64) string { return strconv.Itoa(int(s)) }
+
+	return append(os.Environ(),
+		"PLUMADORO_EVENT="      + p.Event,
+		"PLUMADORO_PHASE="      + p.Phase,
+		"PLUMADORO_KIND="       + p.Kind,
+		"PLUMADORO_N="          + strconv.FormatUint(p.N, 10),
+		"PLUMADORO_CYCLE="      + strconv.Itoa(p.Cycle),
+		"PLUMADORO_SESSION="    + strconv.Itoa(p.Session),
+		"PLUMADORO_DURATION="   + seconds(p.Duration),
+		"PLUMADORO_REMAINING="  + seconds(p.Remaining),
+		"PLUMADORO_PAUSED="     + seconds(p.Paused),
+		"PLUMADORO_RUNNING="    + strconv.FormatBool(p.Running),
+		"PLUMADORO_PROFILE="    + p.Profile,
+		"PLUMADORO_TASK="       + p.Task,
+		"PLUMADORO_TASK_TITLE=" + p.TaskTitle,
+		"PLUMADORO_TAGS="       + strings.Join(p.Tags, " "),
+	)
+}
+
+// Runs the hook and waits for it, at most for the timeout of the config
+func (h hookRun) run() error {
+	payload, err := json.Marshal(h.payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), Config.Hooks.Timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.command)
+	cmd.Env    = h.env()
+	cmd.Stdin  = bytes.NewReader(payload)
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // a child keeping stderr open doesn't block it
+
+	// The whole process group is killed on timeout, not only sh
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("timed out after %s", Config.Hooks.Timeout)
+		}
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			err = fmt.Errorf("%w: %s", err, output)
+		}
+		return fmt.Errorf("%w: %s: %w", ErrHookFailed, h.name, err)
+	}
+
+	return nil
+}
+
+// Queues the hooks after the ones of the earlier events. NOTE: it's called from the loop of the
+// events so the queue is in their order
+func (r *hookRunner) run(runs []hookRun) {
+	if len(runs) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queue = append(r.queue, runs...)
+	if !r.running {
+		r.running = true
+		go r.drain()
+	}
+}
+
+func (r *hookRunner) drain() {
+	for {
+		r.mu.Lock()
+		if len(r.queue) == 0 {
+			r.running = false
+			r.idle.Broadcast()
+			r.mu.Unlock()
+			return
+		}
+		h := r.queue[0]
+		r.queue = r.queue[1:]
+		r.mu.Unlock()
+
+		if err := h.run(); err != nil {
+			select {
+			case r.failures <- err.Error():
+			default: // nobody is taking them
+			}
+		}
+	}
+}
+
+// Waits for the queued hooks to be run, e.g. the on_quit hook before exiting
+func (r *hookRunner) wait() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for r.running {
+		r.idle.Wait()
+	}
+}
+
+// Waits for a hook to fail
+func waitHookFailure() tea.Cmd {
+	failures := hooks.failures
+	return func() tea.Msg { return HookFailedMsg{Content: <-failures} }
+}
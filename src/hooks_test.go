@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHookName(t *testing.T) {
+	tests := []struct {
+		event  string
+		kind   phaseType
+		name   string
+	}{
+		{PhaseStartedEvent, Focus, "on_focus_start"},
+		{PhaseStartedEvent, ShortBreak, "on_break_start"},
+		{PhaseStartedEvent, LongBreak, "on_break_start"},
+		{PausedEvent, Focus, "on_pause"},
+		{ResumedEvent, Focus, "on_resume"},
+		{SkippedEvent, ShortBreak, "on_skip"},
+		{ResetEvent, Focus, "on_reset"},
+		{AppQuitEvent, Focus, "on_quit"},
+		{CompletedEvent, Focus, ""},
+		{TaskSwitchedEvent, Focus, ""},
+	}
+
+	for _, test := range tests {
+		if got := hookName(pomodoroEvent{event: test.event, kind: test.kind}); got != test.name {
+			t.Errorf("hookName(%s of %s) = %q, want %q", test.event, test.kind, got, test.name)
+		}
+	}
+}
+
+func TestHookEnv(t *testing.T) {
+	h := hookRun{name: "on_pause", payload: hookPayload{
+		Event:     PausedEvent,
+		Phase:     "focus",
+		Kind:      "focus",
+		N:         3,
+		Cycle:     1,
+		Session:   2,
+		Duration:  1500,
+		Remaining: 599.7,
+		Paused:    12,
+		Running:   false,
+		Profile:   "deep_work",
+		Task:      "42",
+		TaskTitle: "Write the report",
+		Tags:      []string{"+work", "@desk"},
+	}}
+
+	env := h.env()
+	for _, want := range []string{
+		"PLUMADORO_EVENT=paused",
+		"PLUMADORO_PHASE=focus",
+		"PLUMADORO_N=3",
+		"PLUMADORO_CYCLE=1",
+		"PLUMADORO_SESSION=2",
+		"PLUMADORO_DURATION=1500",
+		"PLUMADORO_REMAINING=599",
+		"PLUMADORO_PAUSED=12",
+		"PLUMADORO_RUNNING=false",
+		"PLUMADORO_PROFILE=deep_work",
+		"PLUMADORO_TASK=42",
+		"PLUMADORO_TASK_TITLE=Write the report",
+		"PLUMADORO_TAGS=+work @desk",
+	} {
+		if !slices.Contains(env, want) {
+			t.Errorf("%s isn't in the environment", want)
+		}
+	}
+}
+
+func TestHookRun(t *testing.T) {
+	useConfig(t, defaultConfig)
+	out := filepath.Join(t.TempDir(), "out")
+
+	h := hookRun{
+		name:    "on_skip",
+		command: `printf '%s ' "$PLUMADORO_EVENT" > "$OUT"; cat >> "$OUT"`,
+		payload: hookPayload{Event: SkippedEvent, Phase: "focus"},
+	}
+	t.Setenv("OUT", out)
+
+	if err := h.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(out)
+	if got := string(data); !strings.HasPrefix(got, `skipped {"event":"skipped"`) {
+		t.Errorf("the hook wrote %q, want the event's variable then its JSON", got)
+	}
+
+	failing := hookRun{name: "on_reset", command: "echo broken >&2; exit 3"}
+	if err := failing.run(); !errors.Is(err, ErrHookFailed) || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("run() = %v, want a failure with the hook's stderr", err)
+	}
+}
+
+// The hook and the processes it started are killed after the timeout
+func TestHookTimeout(t *testing.T) {
+	useConfig(t, defaultConfig)
+	Config.Hooks.Timeout = time.Millisecond * 200
+
+	pidPath := filepath.Join(t.TempDir(), "pid")
+	h := hookRun{name: "on_quit", command: `sleep 30 & echo $! > "` + pidPath + `"; wait`}
+
+	start := time.Now()
+	err := h.run()
+
+	if !errors.Is(err, ErrHookFailed) || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("run() = %v, want a time out", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second * 5 {
+		t.Errorf("run() took %s, want it killed after the timeout", elapsed)
+	}
+
+	data, err := os.ReadFile(pidPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+
+	// The child of sh is killed too, it can take a moment to be reaped (or never be, by a container's init)
+	for deadline := time.Now().Add(time.Second * 5); ; time.Sleep(time.Millisecond * 20) {
+		stat, _ := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if syscall.Kill(pid, 0) == syscall.ESRCH || strings.Contains(string(stat), ") Z ") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the child %d of the hook is still running", pid)
+		}
+	}
+}
+
+// The hooks of events queued one after the other run in that order and don't overlap
+func TestHookRunnerOrder(t *testing.T) {
+	useConfig(t, defaultConfig)
+	out := filepath.Join(t.TempDir(), "out")
+	t.Setenv("OUT", out)
+
+	r := newHookRunner()
+	r.run([]hookRun{{name: "on_pause", command: `echo pause-start >> "$OUT"; sleep 0.2; echo pause-end >> "$OUT"`}})
+	r.run([]hookRun{{name: "on_resume", command: `echo resume >> "$OUT"`}})
+	r.run([]hookRun{{name: "on_reset", command: "exit 1"}})
+	r.wait()
+
+	data, _ := os.ReadFile(out)
+	if got := string(data); got != "pause-start\npause-end\nresume\n" {
+		t.Errorf("the hooks wrote %q, want on_pause then on_resume", got)
+	}
+
+	select {
+	case failure := <-r.failures:
+		if !strings.Contains(failure, "on_reset") {
+			t.Errorf("failure %q, want the one of on_reset", failure)
+		}
+	default:
+		t.Error("the failure of on_reset wasn't reported")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A single line of text typed in a submodel (bubbles' textinput needs a clipboard package)
+type lineInput struct {
+	runes    []rune
+	maxLen   int
+}
+
+// Edits the line with a key, it returns whether enter (submitted) or esc (cancelled) was pressed
+func (in *lineInput) update(msg tea.KeyMsg) (submitted bool, cancelled bool) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		return false, true
+
+	case tea.KeyEnter:
+		return true, false
+
+	case tea.KeyBackspace:
+		if len(in.runes) != 0 {
+			in.runes = in.runes[:len(in.runes) - 1]
+		}
+
+	case tea.KeyRunes, tea.KeySpace:
+		if len(in.runes) + len(msg.Runes) <= in.maxLen {
+			in.runes = append(in.runes, msg.Runes...)
+		}
+	}
+
+	return false, false
+}
+
+func (in *lineInput) reset() {
+	in.runes = nil
+}
+
+func (in *lineInput) value() string {
+	return strings.TrimSpace(string(in.runes))
+}
+
+func (in *lineInput) View() string {
+	return string(in.runes) + "█"
+}
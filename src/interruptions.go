@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Interruptions are recorded during the focus phases like in the Pomodoro Technique, an internal
+// one is something you thought of doing and an external one is someone or something else. Unlike
+// pausing they don't stop the timer
+
+const (
+	InternalInterruption string = "internal"
+	ExternalInterruption string = "external"
+)
+
+// Opens the prompt for the note of an interruption
+type OpenInterruptionMsg struct {
+	Kind string
+}
+
+// Sent by the prompt when the note was typed, the note can be empty
+type InterruptionMsg struct {
+	Kind string
+	Note string
+}
+
+var ErrNotFocusing = errors.New("Interruptions are only recorded during focus phases")
+
+const maxNoteLen int = 128
+
+type InterruptionModel struct {
+	kind   string
+	input  lineInput
+}
+
+func (m *InterruptionModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *InterruptionModel) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		submitted, cancelled := m.input.update(msg)
+
+		if cancelled {
+			cmd = func() tea.Msg { return InitPomodoroMsg{} }
+		} else if submitted {
+			interruption := InterruptionMsg{Kind: m.kind, Note: m.input.value()}
+			cmd = func() tea.Msg { return interruption }
+		}
+
+	case OpenInterruptionMsg:
+		m.kind  = msg.Kind
+		m.input = lineInput{maxLen: maxNoteLen}
+	}
+
+	return cmd
+}
+
+func (m *InterruptionModel) Render() string {
+	color := Config.ProgressBar.FocusColor
+
+	title := "Interruption"
+	if m.kind != "" {
+		title = fmt.Sprintf("%s interruption", strings.ToUpper(m.kind[:1]) + m.kind[1:])
+	}
+
+	s := GetBorderStyle(color).Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render(title),
+			"",
+			"Note: " + m.input.View(),
+			"",
+			"[enter] record [esc] cancel",
+		),
+	)
+
+	return s
+}
+
+// Asks for the note of an interruption, it's only asked during focus phases
+func (m *PomodoroModel) openInterruption(kind string) tea.Cmd {
+	if m.getPhase().kind != Focus {
+		return func() tea.Msg { return PopupMsg{Type: WarningPopup, Content: ErrNotFocusing.Error()} }
+	}
+
+	return func() tea.Msg { return OpenInterruptionMsg{Kind: kind} }
+}
+
+// Records an interruption of the current focus phase
+func (m *PomodoroModel) interrupt(kind string, note string) error {
+	if m.getPhase().kind != Focus {
+		return ErrNotFocusing
+	}
+
+	switch (kind) {
+	case InternalInterruption:
+		m.internalInterruptions++
+		m.emitNote(InternalInterruptionEvent, note)
+	case ExternalInterruption:
+		m.externalInterruptions++
+		m.emitNote(ExternalInterruptionEvent, note)
+	default:
+		return fmt.Errorf("%w: interrupt takes \"internal\" or \"external\"", ErrUnknownAction)
+	}
+
+	return nil
+}
+
+// The interruptions of the last phase in the events, the events are in the order they were written
+func countInterruptions(events []pomodoroEvent) (internal int, external int) {
+	for _, e := range events {
+		switch (e.event) {
+		case PhaseStartedEvent, ResetEvent, ProfileSwitchedEvent:
+			internal, external = 0, 0
+		case InternalInterruptionEvent:
+			internal++
+		case ExternalInterruptionEvent:
+			external++
+		}
+	}
+
+	return internal, external
+}
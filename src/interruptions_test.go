@@ -0,0 +1,14 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderInterruptionWithoutKind(t *testing.T) {
+	m := &InterruptionModel{}
+
+	if view := m.Render(); !strings.Contains(view, "Interruption") {
+		t.Errorf("Render() = %q, want the interruption prompt", view)
+	}
+}
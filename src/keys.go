@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The keys of the timer's actions, the names are the ones of bubbletea's KeyMsg.String()
+// except the space bar which is "space"
+type KeysConfigT struct {
+	Toggle                []string  `toml:"toggle"`
+	Reset                 []string  `toml:"reset"`
+	Skip                  []string  `toml:"skip"`
+	Profiles              []string  `toml:"profiles"`
+	Stats                 []string  `toml:"stats"`
+	Tasks                 []string  `toml:"tasks"`
+	InternalInterruption  []string  `toml:"internal_interruption"`
+	ExternalInterruption  []string  `toml:"external_interruption"`
+	Silence               []string  `toml:"silence"`
+	Snooze                []string  `toml:"snooze"`
+	Mute                  []string  `toml:"mute"`
+	Help                  []string  `toml:"help"`
+	Quit                  []string  `toml:"quit"`
+}
+
+// Opens the help view
+type OpenHelpMsg struct{}
+
+type keyAction struct {
+	name  string
+	keys  *[]string
+	help  string
+}
+
+func (k *KeysConfigT) actions() []keyAction {
+	return []keyAction{
+		{"toggle",                &k.Toggle,               "start/pause"},
+		{"reset",                 &k.Reset,                "reset the phase"},
+		{"skip",                  &k.Skip,                 "skip the phase"},
+		{"profiles",              &k.Profiles,             "profiles"},
+		{"stats",                 &k.Stats,                "statistics"},
+		{"tasks",                 &k.Tasks,                "tasks"},
+		{"internal_interruption", &k.InternalInterruption, "internal interruption"},
+		{"external_interruption", &k.ExternalInterruption, "external interruption"},
+		{"silence",               &k.Silence,              "stop the alarm"},
+		{"snooze",                &k.Snooze,               "snooze the alarm"},
+		{"mute",                  &k.Mute,                 "mute the ambient sound"},
+		{"help",                  &k.Help,                 "help"},
+		{"quit",                  &k.Quit,                 "quit"},
+	}
+}
+
+// An action without keys is disabled, except quitting. A key bound to two actions is an error and
+// the keys fall back to the defaults
+func validateKeys(errsPtr *[]error) {
+	actions := Config.Keys.actions()
+	defaults := defaultConfig.Keys.actions()
+
+	var errs []error
+	boundTo := map[string]string{}
+
+	for _, action := range actions {
+		for _, k := range *action.keys {
+			if k == "" {
+				errs = append(errs, fmt.Errorf("Invalid keys.%s: a key can't be empty", action.name))
+				continue
+			}
+
+			if other, ok := boundTo[k]; ok {
+				errs = append(errs, fmt.Errorf("Invalid keys.%s: %q is bound to keys.%s too", action.name, k, other))
+				continue
+			}
+			boundTo[k] = action.name
+		}
+	}
+
+	if len(Config.Keys.Quit) == 0 {
+		errs = append(errs, fmt.Errorf("Invalid keys.quit: it needs at least one key"))
+	}
+
+	if len(errs) != 0 {
+		for i, action := range actions {
+			*action.keys = *defaults[i].keys
+		}
+		*errsPtr = append(*errsPtr, errs...)
+	}
+}
+
+// The names of the config as KeyMsg.String() names them
+func keyNames(keys []string) []string {
+	names := make([]string, len(keys))
+
+	for i, k := range keys {
+		if k == "space" {
+			k = " "
+		}
+		names[i] = k
+	}
+
+	return names
+}
+
+type keyMap struct {
+	Toggle                key.Binding
+	Reset                 key.Binding
+	Skip                  key.Binding
+	Profiles              key.Binding
+	Stats                 key.Binding
+	Tasks                 key.Binding
+	InternalInterruption  key.Binding
+	ExternalInterruption  key.Binding
+	Silence               key.Binding
+	Snooze                key.Binding
+	Mute                  key.Binding
+	Help                  key.Binding
+	Quit                  key.Binding
+}
+
+// The bindings of the current config
+func newKeyMap() keyMap {
+	binding := func(keys []string, desc string) key.Binding {
+		b := key.NewBinding(
+			key.WithKeys(keyNames(keys)...),
+			key.WithHelp(strings.Join(keys, "/"), desc),
+		)
+		b.SetEnabled(len(keys) != 0)
+		return b
+	}
+
+	k := &Config.Keys
+	bindings := map[string]key.Binding{}
+	for _, action := range k.actions() {
+		bindings[action.name] = binding(*action.keys, action.help)
+	}
+
+	return keyMap{
+		Toggle:               bindings["toggle"],
+		Reset:                bindings["reset"],
+		Skip:                 bindings["skip"],
+		Profiles:             bindings["profiles"],
+		Stats:                bindings["stats"],
+		Tasks:                bindings["tasks"],
+		InternalInterruption: bindings["internal_interruption"],
+		ExternalInterruption: bindings["external_interruption"],
+		Silence:              bindings["silence"],
+		Snooze:               bindings["snooze"],
+		Mute:                 bindings["mute"],
+		Help:                 bindings["help"],
+		Quit:                 bindings["quit"],
+	}
+}
+
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Toggle, k.Help, k.Quit}
+}
+
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Toggle, k.Reset, k.Skip},
+		{k.Profiles, k.Stats, k.Tasks},
+		{k.InternalInterruption, k.ExternalInterruption},
+		{k.Silence, k.Snooze, k.Mute},
+		{k.Help, k.Quit},
+	}
+}
+
+type HelpModel struct {
+	keys  keyMap
+	help  help.Model
+}
+
+func (m *HelpModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *HelpModel) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "q" || msg.String() == "esc" || key.Matches(msg, m.keys.Help) {
+			cmd = func() tea.Msg { return InitPomodoroMsg{} }
+		}
+
+	case OpenHelpMsg:
+		m.keys = newKeyMap()
+		m.help = help.New()
+		m.help.ShowAll = true
+	}
+
+	return cmd
+}
+
+func (m *HelpModel) Render() string {
+	color := Config.ProgressBar.FocusColor
+
+	s := GetBorderStyle(color).Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render("Keys"),
+			"",
+			m.help.View(m.keys),
+		),
+	)
+
+	return s
+}
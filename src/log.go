@@ -1,31 +1,60 @@
 package main
 
 import (
+	"crypto/rand"
 	"encoding/csv"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"errors"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
 )
 
-type LogTickMsg time.Time
+// The log is append only, a row is written for every event with the state of the timer right after
+// it, so the state can be restored by replaying the events of the day
+
+// The events written to the log
+const (
+	PhaseStartedEvent         string = "phase_started"
+	PausedEvent               string = "paused"
+	ResumedEvent              string = "resumed"
+	SkippedEvent              string = "skipped"
+	ResetEvent                string = "reset"
+	CompletedEvent            string = "completed"
+	ProfileSwitchedEvent      string = "profile_switched"
+	AppQuitEvent              string = "app_quit"
+	TaskSwitchedEvent         string = "task_switched"
+	InternalInterruptionEvent string = "internal_interruption"
+	ExternalInterruptionEvent string = "external_interruption"
+)
 
-type pomodoroRecord struct {
+type pomodoroEvent struct {
+	time_            time.Time
+	session          string // ID of the run of plumadoro that wrote the event
+	event            string
+	phase            string // name of the phase
+	kind             phaseType
+	n                uint64
+	duration         time.Duration
 	remainingTime    time.Duration
 	pausedTime       time.Duration
-	phaseType        phaseType
-	n                uint64
 	running          bool
-	time_            time.Time
+	profile          string
+	task             string // ID of the active task, empty for no task
+	tags             []string // +project & @context of the active task
+	note             string // the note of an interruption
 }
 
 var (
 	ErrStateNotRestorable  = errors.New("Cannot restore last state because it's not in the same day")
 	ErrFailedReadingLog    = errors.New("Failed reading the configuration path no log file found")
 	ErrFailedParsingLog    = errors.New("Failed parsing a CSV row in the log file")
+	ErrPhasesChanged       = errors.New("Cannot restore last state because the phases sequence has changed")
 )
 
 var cacheDir, _ = os.UserCacheDir()
@@ -34,133 +63,289 @@ var logPath string = fmt.Sprintf("%s/.plumadoro_log.csv", cacheDir)
 
 const timeFormat string = time.RFC3339
 
-const LogTickDuration time.Duration = time.Second * 30 // XXX: hardcoded i know
+// The columns every event has, the snapshot rows written before the events have 6 or 7.
+// The columns added after them (task, tags, note) are optional so the older events can still be read
+const eventColumns int = 11
 
+// Generated once per run so the events of different runs can be told apart
+var sessionID string = newSessionID()
 
-func fromCSVRow(row []string) (pomodoroRecord, error) {
-	var record pomodoroRecord
-	var err error
+func newSessionID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
 
-	// 6 is the count of pomodoroRecord's fields
-	if len(row) != 6 {
-		return record, fmt.Errorf("%w: Invalid length for row it must be 6 cols only.", ErrFailedParsingLog)
+func isLegacyRow(row []string) bool {
+	return len(row) == 6 || len(row) == 7
+}
+
+
+func fromCSVRow(row []string) (pomodoroEvent, error) {
+	var e pomodoroEvent
+	var errs [7]error
+
+	if len(row) < eventColumns {
+		return e, fmt.Errorf("%w: Invalid length for row it must be %d cols.", ErrFailedParsingLog, eventColumns)
 	}
 
-	var phase phaseType
-	switch (row[2]) {
-	case "focus"      :  phase = Focus
-	case "short_break":  phase = ShortBreak
-	case "long_break" :  phase = LongBreak
+	var ok bool
+
+	e.time_, errs[0]         = time.Parse(timeFormat, row[0])
+	e.session                = row[1]
+	e.event                  = row[2]
+	e.phase                  = row[3]
+	e.kind, ok               = parsePhaseType(row[4])
+	e.n, errs[1]             = strconv.ParseUint(row[5], 10, 64)
+	e.duration, errs[2]      = time.ParseDuration(row[6])
+	e.remainingTime, errs[3] = time.ParseDuration(row[7])
+	e.pausedTime, errs[4]    = time.ParseDuration(row[8])
+	e.running, errs[5]       = strconv.ParseBool(row[9])
+	e.profile                = row[10]
+
+	// The events without a task had a 0 before the tasks could come from other sources
+	if len(row) > 11 && row[11] != "0" {
+		e.task = row[11]
+	}
+	if len(row) > 12 {
+		e.tags = strings.Fields(row[12])
+	}
+	if len(row) > 13 {
+		e.note = row[13]
 	}
 
-	record.remainingTime, err = time.ParseDuration(row[0])
-	record.pausedTime, err    = time.ParseDuration(row[1])
-	record.phaseType          = phase            // row[2]
-	record.n, err             = strconv.ParseUint(row[3], 10, 8)
-	record.running, err       = strconv.ParseBool(row[4])
-	record.time_, err         = time.Parse(timeFormat, row[5])
+	if !ok {
+		errs[6] = fmt.Errorf("Unknown phase kind %q", row[4])
+	}
 
-	if err != nil {
-		return record, ErrFailedParsingLog
+	if err := errors.Join(errs[:]...); err != nil {
+		return e, fmt.Errorf("%w: %w", ErrFailedParsingLog, err)
 	}
 
-	return record, nil
+	return e, nil
 }
 
-func (r pomodoroRecord) toCSVRow() []string {
-	var phaseTypeStr string
-	switch (r.phaseType) {
-	case Focus:         phaseTypeStr = "focus"
-	case ShortBreak:    phaseTypeStr = "short_break"
-	case LongBreak:     phaseTypeStr = "long_break"
+func (e pomodoroEvent) toCSVRow() []string {
+	return []string{
+		e.time_.Format(timeFormat),
+		e.session,
+		e.event,
+		e.phase,                                     // Phase name
+		e.kind.String(),                             // Phase kind
+		strconv.FormatUint(e.n, 10),                 // N of the current phase
+		e.duration.Round(time.Second).String(),      // Duration of the phase
+		e.remainingTime.Round(time.Second).String(), // Remaning time
+		e.pausedTime.Round(time.Second).String(),    // Paused time
+		strconv.FormatBool(e.running),               // Running
+		e.profile,
+		e.task,                                      // Active task
+		strings.Join(e.tags, " "),                   // Tags of the active task
+		e.note,                                      // Note of an interruption
 	}
+}
 
-	return []string{
-		r.remainingTime.Round(time.Second).String(), // Remaning time
-		r.pausedTime.Round(time.Second).String(),    // Paused time
-		phaseTypeStr,                                // Phase type
-		strconv.FormatUint(r.n, 10),                                 // N of the current phase
-		strconv.FormatBool(r.running),               // Running
-		r.time_.Format(timeFormat),
+// Queues an event with the current state, it's written to the log by flush()
+func (p *PomodoroModel) emit(event string) {
+	// The daemon logs the events of the TUIs attached to it
+	if p.remote != nil {
+		return
+	}
+
+	phase := p.getPhase()
+	remaining := p.remainingTime
+	if p.running {
+		remaining = p.deadline.Sub(p.clock.Now())
+	}
+
+	p.pending = append(p.pending, pomodoroEvent{
+		time_:          p.clock.Now(),
+		session:        sessionID,
+		event:          event,
+		phase:          phase.name,
+		kind:           phase.kind,
+		n:              p.n,
+		duration:       phase.duration,
+		remainingTime:  max(remaining, 0),
+		pausedTime:     p.pausedTime,
+		running:        p.running,
+		profile:        Config.Profile,
+		task:           p.task.ID,
+		tags:           p.task.Tags,
+	})
+}
+
+// Queues an event with a note
+func (p *PomodoroModel) emitNote(event string, note string) {
+	count := len(p.pending)
+	p.emit(event)
+
+	if len(p.pending) > count {
+		p.pending[len(p.pending) - 1].note = note
+	}
+}
+
+// Writes the queued events to the log
+func (p *PomodoroModel) flush() error {
+	if len(p.pending) == 0 {
+		return nil
 	}
+
+	events := p.pending
+	p.pending = nil
+
+	return appendEvents(events)
 }
 
-// Should only be used twice when the pomodor app terminates and when a phase ends
-func (p *PomodoroModel) save() error {
-	var err error = nil
-	var file *os.File
+func appendEvents(events []pomodoroEvent) error {
+	if err := rotateLog(events[0].time_); err != nil {
+		return err
+	}
 
-	// HACK: IDK how the hell this works but i don't give a s***
-	file, err = os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.ModeAppend)
+	file, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		return ErrFailedReadingLog
+		return fmt.Errorf("%w: %w", ErrFailedReadingLog, err)
 	}
 	defer file.Close()
 
 	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	err = writer.Write(pomodoroRecord{
-		remainingTime: p.remainingTime,
-		pausedTime:    p.pausedTime,
-		phaseType:     p.phaseType,
-		n:             uint64(p.n),
-		running:       p.running,
-		time_:         time.Now(),
-		}.toCSVRow(),
-	)
+	for _, e := range events {
+		writer.Write(e.toCSVRow())
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
 
+func readRows(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // the rows before the events existed are shorter
+	rows, err := reader.ReadAll()
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedParsingLog, err)
+		return nil, fmt.Errorf("%w: %w", ErrFailedParsingLog, err)
 	}
 
-	return err
+	return rows, nil
 }
 
-// TODO: use Seek and stat to read the last line only instead of the whole file
-func (p *PomodoroModel) restore() error {
-	file, err := os.Open(logPath)
+// Reads the events of the log, the snapshot rows of the older versions are skipped
+func readEvents(r io.Reader) ([]pomodoroEvent, error) {
+	rows, err := readRows(r)
 	if err != nil {
-		return ErrFailedReadingLog
+		return nil, err
+	}
+
+	events := make([]pomodoroEvent, 0, len(rows))
+	for _, row := range rows {
+		if isLegacyRow(row) {
+			continue
+		}
+
+		e, err := fromCSVRow(row)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	return events, nil
+}
+
+func readLastEvent() (pomodoroEvent, error) {
+	count := 0
+	events, err := readTailEvents(func(e pomodoroEvent) bool {
+		count++
+		return count == 1
+	})
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedParsingLog, err)
+		return pomodoroEvent{}, err
 	}
 
-	if len(records) == 0 {
-		return fmt.Errorf("%w: %w", ErrFailedParsingLog, "Log is empty.")
+	if len(events) == 0 {
+		return pomodoroEvent{}, fmt.Errorf("%w: Log is empty.", ErrFailedParsingLog)
 	}
 
-	record, err := fromCSVRow(records[len(records) - 1])
+	return events[len(events) - 1], nil
+}
+
+// Replays the events in order and returns the state the timer was in after the last one
+func replay(events []pomodoroEvent) pomodoroEvent {
+	var state pomodoroEvent
+
+	for _, e := range events {
+		switch (e.event) {
+		case PhaseStartedEvent, ResetEvent, ProfileSwitchedEvent:
+			// A fresh phase
+			state = e
+
+		case CompletedEvent, SkippedEvent:
+			// The phase_started event of the next phase follows, this one only moves the time
+			state.time_ = e.time_
+
+		case AppQuitEvent:
+			state = e
+			state.running = false // the clock doesn't run while plumadoro is closed
+
+		default:
+			state = e
+		}
+	}
+
+	return state
+}
+
+// The state an event leaves the timer in at now. A timer running without quitting (a crash, a
+// SIGKILL...) is counted as running since the event, until the end of its phase
+func (e pomodoroEvent) at(now time.Time) pomodoroEvent {
+	if e.running && e.event != AppQuitEvent {
+		e.remainingTime = max(e.remainingTime - now.Sub(e.time_), 0)
+		e.time_         = now
+	}
+
+	return e
+}
+
+func (p *PomodoroModel) restore() error {
+	// Only the events of the current day are replayed
+	now := p.clock.Now()
+	events, err := readTailEvents(func(e pomodoroEvent) bool {
+		return sameDay(e.time_, now)
+	})
 	if err != nil {
 		return err
 	}
 
-	now := time.Now()
-	year, month, day := record.time_.Date()
-
-	if year != now.Year() || month != now.Month() || day != now.Day() {
+	if len(events) == 0 {
 		return ErrStateNotRestorable
 	}
 
-	p.remainingTime     = record.remainingTime
-	p.pausedTime        = record.pausedTime
-	p.phaseType         = record.phaseType
-	p.running           = Config.Autostart
-	p.n                 = uint8(record.n)
-	p.phasesDurations   = map[phaseType]time.Duration{
-		Focus:      Config.Durations.Focus,
-		ShortBreak: Config.Durations.ShortBreak,
-		LongBreak:  Config.Durations.LongBreak,
+	state := replay(events).at(now)
+	p.internalInterruptions, p.externalInterruptions = countInterruptions(events)
+
+	// The phases are built from the logged profile (if it still exists) unless a profile was
+	// picked from the command line
+	if state.profile != "" && !Config.profileFromFlag {
+		UseProfile(state.profile)
+	}
+
+	phases := buildPhases()
+	if state.n == 0 || phases[(state.n - 1) % uint64(len(phases))].name != state.phase {
+		return ErrPhasesChanged
 	}
+
+	p.remainingTime     = state.remainingTime
+	p.pausedTime        = state.pausedTime
+	p.running           = false
+	p.n                 = state.n
+	p.phases            = phases
 	p.progressBar       = progress.New(
 		progress.WithSolidFill(p.getPhaseColor()),
 	)
+	// A task that isn't in the task source anymore is still logged with the next events
+	if err := p.setTask(state.task); err != nil {
+		p.task = taskItem{ID: state.task, Title: state.task, Tags: state.tags}
+	}
+	p.setRunning(Config.Autostart)
 
 	return nil
 }
-
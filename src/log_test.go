@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// An event of the default profile's phases at the given minute after 9:00
+func testEvent(minute int, event string, n uint64, remaining time.Duration, paused time.Duration, running bool) pomodoroEvent {
+	phases := buildPhases()
+	phase := phases[(n - 1) % uint64(len(phases))]
+
+	return pomodoroEvent{
+		time_:          newFakeClock().Now().Add(time.Duration(minute) * time.Minute),
+		session:        "test",
+		event:          event,
+		phase:          phase.name,
+		kind:           phase.kind,
+		n:              n,
+		duration:       phase.duration,
+		remainingTime:  remaining,
+		pausedTime:     paused,
+		running:        running,
+		profile:        DefaultProfile,
+	}
+}
+
+func TestReplay(t *testing.T) {
+	useConfig(t, defaultConfig)
+
+	started := testEvent(0, PhaseStartedEvent, 1, time.Minute * 25, 0, true)
+	paused  := testEvent(5, PausedEvent, 1, time.Minute * 20, 0, false)
+	resumed := testEvent(7, ResumedEvent, 1, time.Minute * 20, time.Minute * 2, true)
+
+	tests := []struct {
+		name    string
+		events  []pomodoroEvent
+		want    pomodoroEvent
+	}{
+		{"started", []pomodoroEvent{started}, started},
+		{"paused", []pomodoroEvent{started, paused}, paused},
+		{"resumed", []pomodoroEvent{started, paused, resumed}, resumed},
+		{
+			"next phase",
+			[]pomodoroEvent{
+				started,
+				testEvent(25, CompletedEvent, 1, 0, 0, true),
+				testEvent(25, PhaseStartedEvent, 2, time.Minute * 5, 0, false),
+			},
+			testEvent(25, PhaseStartedEvent, 2, time.Minute * 5, 0, false),
+		},
+		{
+			"quit while running",
+			[]pomodoroEvent{started, testEvent(3, AppQuitEvent, 1, time.Minute * 22, 0, true)},
+			testEvent(3, AppQuitEvent, 1, time.Minute * 22, 0, false),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := replay(test.events)
+
+			if got.event != test.want.event || got.n != test.want.n || got.running != test.want.running ||
+				got.remainingTime != test.want.remainingTime || got.pausedTime != test.want.pausedTime {
+				t.Errorf("replay() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	tests := []struct {
+		name       string
+		events     []pomodoroEvent
+		after      time.Duration // from 9:00 to the restore
+		err        error
+		n          uint64
+		remaining  time.Duration
+		paused     time.Duration
+	}{
+		{
+			name:      "same day",
+			events:    []pomodoroEvent{
+				testEvent(0, PhaseStartedEvent, 3, time.Minute * 25, 0, true),
+				testEvent(10, PausedEvent, 3, time.Minute * 15, 0, false),
+				testEvent(12, AppQuitEvent, 3, time.Minute * 15, time.Minute * 2, false),
+			},
+			after:     time.Hour,
+			n:         3,
+			remaining: time.Minute * 15,
+			paused:    time.Minute * 2,
+		},
+		{
+			name:      "killed while running",
+			events:    []pomodoroEvent{testEvent(0, PhaseStartedEvent, 3, time.Minute * 25, 0, true)},
+			after:     time.Minute * 10,
+			n:         3,
+			remaining: time.Minute * 15,
+		},
+		{
+			name:   "killed past the end of the phase",
+			events: []pomodoroEvent{testEvent(0, PhaseStartedEvent, 3, time.Minute * 25, 0, true)},
+			after:  time.Hour,
+			n:      3,
+		},
+		{
+			name:   "another day",
+			events: []pomodoroEvent{testEvent(0, PhaseStartedEvent, 3, time.Minute * 25, 0, true)},
+			after:  time.Hour * 24,
+			err:    ErrStateNotRestorable,
+		},
+		{
+			name:   "phases changed",
+			events: []pomodoroEvent{func() pomodoroEvent {
+				e := testEvent(0, PhaseStartedEvent, 3, time.Minute * 25, 0, true)
+				e.phase = "review"
+				return e
+			}()},
+			after: time.Hour,
+			err:   ErrPhasesChanged,
+		},
+		{
+			name:  "no log",
+			after: time.Hour,
+			err:   ErrFailedReadingLog,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useConfig(t, defaultConfig)
+			useLog(t)
+
+			if len(test.events) != 0 {
+				if err := appendEvents(test.events); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			clock := newFakeClock()
+			clock.advance(test.after)
+			m := NewPomodoroModel(clock)
+
+			err := m.restore()
+			if !errors.Is(err, test.err) {
+				t.Fatalf("restore() = %v, want %v", err, test.err)
+			}
+			if err != nil {
+				return
+			}
+
+			if m.n != test.n || m.remainingTime != test.remaining || m.pausedTime != test.paused || m.running {
+				t.Errorf("restored n %d remaining %s paused %s running %v, want %d %s %s false",
+					m.n, m.remainingTime, m.pausedTime, m.running, test.n, test.remaining, test.paused)
+			}
+		})
+	}
+}
+
+// The status and the restored timer agree on the time of a timer that didn't quit
+func TestRecordAt(t *testing.T) {
+	useConfig(t, defaultConfig)
+
+	clock := newFakeClock()
+	record := testEvent(0, PhaseStartedEvent, 1, time.Minute * 25, 0, true)
+	clock.advance(time.Minute * 10)
+
+	if got := record.at(clock.Now()).remainingTime; got != time.Minute * 15 {
+		t.Errorf("at() remaining %s, want 15m", got)
+	}
+
+	state := stateFromRecord(record, clock.Now())
+	if state.Remaining != (time.Minute * 15).Seconds() {
+		t.Errorf("stateFromRecord() remaining %g, want 900", state.Remaining)
+	}
+
+	quit := testEvent(0, AppQuitEvent, 1, time.Minute * 25, 0, true)
+	if got := quit.at(clock.Now()).remainingTime; got != time.Minute * 25 {
+		t.Errorf("at() remaining %s after app_quit, want 25m", got)
+	}
+}
+
+// A segment of the same month as an older one is rotated next to it and read after it
+func TestRotateLogSegmentExists(t *testing.T) {
+	useConfig(t, defaultConfig)
+	useLog(t)
+
+	// Every write is in March after a log last modified in February, so each one rotates it
+	february := newFakeClock().Now().AddDate(0, -1, 0)
+
+	for n := uint64(1); n <= 2; n++ {
+		if err := appendEvents([]pomodoroEvent{testEvent(0, PhaseStartedEvent, n, time.Minute * 25, 0, true)}); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(logPath, february, february)
+
+		e := testEvent(0, ResetEvent, n, time.Minute * 25, 0, false)
+		if err := appendEvents([]pomodoroEvent{e}); err != nil {
+			t.Fatalf("rotation %d: %v", n, err)
+		}
+		os.Chtimes(logPath, february, february)
+	}
+
+	base := strings.TrimSuffix(logPath, ".csv")
+	want := []string{base + "-2026-02.csv.gz", base + "-2026-02_2.csv.gz", base + "-2026-02_3.csv.gz"}
+	if segments := logSegments(); !slices.Equal(segments, want) {
+		t.Errorf("logSegments() = %q, want %q", segments, want)
+	}
+
+	events, err := readAllEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uint64
+	for _, e := range events {
+		got = append(got, e.n)
+	}
+	if !slices.Equal(got, []uint64{1, 1, 2, 2}) {
+		t.Errorf("readAllEvents() sessions %v, want [1 1 2 2]", got)
+	}
+}
+
+// The profile of the log doesn't drop the durations given on the command line
+func TestRestoreKeepsOverrides(t *testing.T) {
+	useConfig(t, defaultConfig)
+	useLog(t)
+
+	focus := time.Minute * 50
+	Config.overrides.Focus = &focus
+	Config.overrides.apply(&Config.ProfileConfigT)
+
+	if err := appendEvents([]pomodoroEvent{testEvent(0, PausedEvent, 1, time.Minute * 20, 0, false)}); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := newFakeClock()
+	clock.advance(time.Hour)
+	m := NewPomodoroModel(clock)
+	if err := m.restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	if Config.Durations.Focus != focus || m.getPhase().duration != focus {
+		t.Errorf("focus %s, phase of %s after restoring, want the flag's 50m", Config.Durations.Focus, m.getPhase().duration)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The log is rotated on the first write of a day (so the events of a day always are in the same
+// file) once it's from another month or bigger than the max size, the old segments are gzipped
+// next to it as <log name>-<time>.csv.gz
+
+const tailChunkSize int64 = 4096
+
+// Calls fn on the lines of the file from the last one to the first one until it returns false,
+// a last line without a newline is a record that wasn't completely written so it's skipped
+func readLinesBackwards(file *os.File, fn func(line []byte) bool) error {
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	offset := stat.Size()
+	var rest []byte // the beginning of a line is in the chunk before it
+	last := true
+
+	for offset > 0 {
+		n := min(tailChunkSize, offset)
+		offset -= n
+
+		chunk := make([]byte, n, n + int64(len(rest)))
+		if _, err := file.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return err
+		}
+		chunk = append(chunk, rest...)
+
+		lines := bytes.Split(chunk, []byte("\n"))
+		rest = lines[0]
+
+		for i := len(lines) - 1; i >= 1; i-- {
+			if last {
+				last = false
+				continue // it's empty if the file ends with a newline
+			}
+
+			if len(lines[i]) != 0 && !fn(lines[i]) {
+				return nil
+			}
+		}
+	}
+
+	if !last && len(rest) != 0 {
+		fn(rest)
+	}
+
+	return nil
+}
+
+// Reads the events at the end of the log, it stops at the first event keep returns false for.
+// The events are returned in the order they were written
+func readTailEvents(keep func(e pomodoroEvent) bool) ([]pomodoroEvent, error) {
+	file, err := os.Open(logPath)
+	if err != nil {
+		return nil, ErrFailedReadingLog
+	}
+	defer file.Close()
+
+	var events []pomodoroEvent
+	var parseErr error
+
+	err = readLinesBackwards(file, func(line []byte) bool {
+		row, err := csv.NewReader(bytes.NewReader(line)).Read()
+		if err != nil {
+			parseErr = fmt.Errorf("%w: %w", ErrFailedParsingLog, err)
+			return false
+		}
+
+		// The snapshot rows of the older versions are older than any event
+		if isLegacyRow(row) {
+			return false
+		}
+
+		e, err := fromCSVRow(row)
+		if err != nil {
+			parseErr = err
+			return false
+		}
+
+		if !keep(e) {
+			return false
+		}
+
+		events = append(events, e)
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if parseErr != nil && len(events) == 0 {
+		return nil, parseErr
+	}
+
+	// Reversing them back to the writing order
+	for i, j := 0, len(events) - 1; i < j; i, j = i + 1, j - 1 {
+		events[i], events[j] = events[j], events[i]
+	}
+
+	return events, nil
+}
+
+// The path of a rotated segment of the log, a number is added to its name when a segment of the
+// same time already exists (the clock went back, a log was put back by hand...)
+func logSegmentPath(t time.Time) string {
+	base := strings.TrimSuffix(logPath, ".csv")
+
+	layout := "2006-01-02T150405"
+	if Config.Log.Rotation == "monthly" {
+		layout = "2006-01"
+	}
+
+	name := fmt.Sprintf("%s-%s", base, t.Format(layout))
+	path := name + ".csv.gz"
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = fmt.Sprintf("%s_%d.csv.gz", name, i)
+	}
+}
+
+// Rotates the log if it has to be, now is the time of the event about to be written
+func rotateLog(now time.Time) error {
+	stat, err := os.Stat(logPath)
+	if err != nil || stat.Size() == 0 || Config.Log.Rotation == "none" {
+		return nil // NOTE: a missing log is created by the write
+	}
+
+	modified := stat.ModTime()
+	if sameDay(modified, now) {
+		return nil
+	}
+
+	switch (Config.Log.Rotation) {
+	case "monthly":
+		if modified.Year() == now.Year() && modified.Month() == now.Month() {
+			return nil
+		}
+	case "size":
+		if stat.Size() < int64(Config.Log.MaxSize) * 1024 {
+			return nil
+		}
+	}
+
+	segment := logSegmentPath(modified)
+	if err := gzipFile(logPath, segment); err != nil {
+		return fmt.Errorf("Failed rotating the log: %w", err)
+	}
+
+	return os.Remove(logPath)
+}
+
+func gzipFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// Written to a temporary file first so a failure doesn't leave a broken segment
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(out)
+	_, err = io.Copy(writer, in)
+	if err == nil {
+		err = writer.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, dst)
+}
+
+// The rotated segments of the log from the oldest to the newest
+func logSegments() []string {
+	base := strings.TrimSuffix(logPath, ".csv")
+
+	segments, _ := filepath.Glob(base + "-*.csv.gz") // NOTE: the pattern is always valid
+	sort.Slice(segments, func(i, j int) bool {
+		return segmentSortKey(segments[i]) < segmentSortKey(segments[j])
+	})
+
+	return segments
+}
+
+// Sorts a segment with a number after the one without it, and the numbers as numbers
+func segmentSortKey(segment string) string {
+	name := strings.TrimSuffix(segment, ".csv.gz")
+
+	if i := strings.LastIndex(name, "_"); i != -1 {
+		if n, err := strconv.Atoi(name[i + 1:]); err == nil {
+			return fmt.Sprintf("%s_%09d", name[:i], n)
+		}
+	}
+
+	return name
+}
+
+// Reads the events of the rotated segments and of the current log
+func readAllEvents() ([]pomodoroEvent, error) {
+	var events []pomodoroEvent
+
+	for _, segment := range logSegments() {
+		segmentEvents, err := readSegment(segment)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", segment, err)
+		}
+		events = append(events, segmentEvents...)
+	}
+
+	file, err := os.Open(logPath)
+	if err != nil {
+		// The log was rotated and nothing was written since
+		if os.IsNotExist(err) && len(events) != 0 {
+			return events, nil
+		}
+		return nil, ErrFailedReadingLog
+	}
+	defer file.Close()
+
+	current, err := readEvents(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(events, current...), nil
+}
+
+func readSegment(path string) ([]pomodoroEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return readEvents(reader)
+}
@@ -12,21 +12,25 @@ import (
 )
 
 type MainModel struct {
-	pomodoro    *PomodoroModel
-	popup       *PopupModel
-	// help        *HelpModel
+	pomodoro      *PomodoroModel
+	popup         *PopupModel
+	profiles      *ProfilesModel
+	stats         *StatsModel
+	tasks         *TasksModel
+	interruption  *InterruptionModel
+	help          *HelpModel
 
 	height      int  // HACK: i think uint16 is more suitable
 	width       int
 
+	configErr   error // LoadConfig is called by the CLI before the model starts
+	remote      *daemonClient
+	api         *apiServer // nil when the HTTP API is disabled
+
 	activeSubmodel Submodel
 }
 
 
-func tickLogEvery() tea.Cmd {
-	return tea.Every(LogTickDuration, func(t time.Time) tea.Msg { return LogTickMsg(t) } )
-}
-
 func tickPomodoroEvery() tea.Cmd {
 	return tea.Every(Config.TickDuration, func(t time.Time) tea.Msg { return PomodoroTickMsg(t) } )
 }
@@ -34,13 +38,42 @@ func tickPomodoroEvery() tea.Cmd {
 func (m *MainModel) Init() tea.Cmd {
 	var cmd tea.Cmd
 
-	// Loading the config
 	// Checking the config errors after the popup model has been intialized
-	err := LoadConfig()
-	m.popup    = &PopupModel{}
-	m.pomodoro = &PomodoroModel{}
+	err := m.configErr
+	m.popup        = &PopupModel{}
+	m.profiles     = &ProfilesModel{}
+	m.stats        = &StatsModel{clock: SystemClock}
+	m.tasks        = &TasksModel{clock: SystemClock}
+	m.interruption = &InterruptionModel{}
+	m.help         = &HelpModel{}
+	m.pomodoro = NewPomodoroModel(SystemClock)
+	m.pomodoro.remote = m.remote
+	if goalsEnabled() {
+		// NOTE: there is nothing to count yet if the log can't be read
+		m.pomodoro.goals = newGoalsTracker(SystemClock)
+		m.pomodoro.goals.load()
+	}
 	cmd = m.pomodoro.Init()
 
+	// The daemon gets the clicks on the notifications when attached to one
+	if Config.Notifications.Enabled && m.remote == nil {
+		cmd = tea.Batch(cmd, waitNotificationAction())
+	}
+
+	cmd = tea.Batch(cmd, waitHookFailure())
+
+	// The daemon sends the webhooks when attached to one
+	if len(Config.Webhooks) != 0 && m.remote == nil {
+		cmd = tea.Batch(cmd, waitWebhookWarning())
+	}
+
+	// The daemon serves the HTTP API when attached to one
+	if m.api != nil && m.remote == nil {
+		if err := m.api.listen(); err != nil {
+			cmd = tea.Batch(cmd, func() tea.Msg { return PopupMsg{Type: ErrorPopup, Content: err.Error()} })
+		}
+	}
+
 	if err != nil {
 		cmd = tea.Batch(
 			cmd,
@@ -55,23 +88,120 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd = nil
 
 	switch (m.activeSubmodel) {
-	case m.pomodoro: cmd = m.pomodoro.Update(msg)
-	case m.popup:    cmd = m.popup.Update(msg)
+	case m.pomodoro:     cmd = m.pomodoro.Update(msg)
+	case m.popup:        cmd = m.popup.Update(msg)
+	case m.profiles:     cmd = m.profiles.Update(msg)
+	case m.stats:        cmd = m.stats.Update(msg)
+	case m.tasks:        cmd = m.tasks.Update(msg)
+	case m.interruption: cmd = m.interruption.Update(msg)
+	case m.help:         cmd = m.help.Update(msg)
 	}
 
 
 	switch msg := msg.(type) {
 	case InitPomodoroMsg:
-		if m.activeSubmodel != m.pomodoro {
-			cmd = tea.Batch(cmd, func() tea.Msg { return msg } )
-		}
-		m.activeSubmodel = m.pomodoro
+		cmd = tea.Batch(cmd, m.open(m.pomodoro, msg))
 
 	case PopupMsg:
-		if m.activeSubmodel != m.popup {
-			cmd = tea.Batch(cmd, func() tea.Msg { return msg })
+		cmd = tea.Batch(cmd, m.open(m.popup, msg))
+
+	case OpenProfilesMsg:
+		cmd = tea.Batch(cmd, m.open(m.profiles, msg))
+
+	case OpenStatsMsg:
+		cmd = tea.Batch(cmd, m.open(m.stats, msg))
+
+	case OpenTasksMsg:
+		if m.activeSubmodel != m.tasks {
+			m.tasks.active = m.pomodoro.task.ID
+		}
+		cmd = tea.Batch(cmd, m.open(m.tasks, msg))
+
+	case OpenHelpMsg:
+		cmd = tea.Batch(cmd, m.open(m.help, msg))
+
+	case OpenInterruptionMsg:
+		cmd = tea.Batch(cmd, m.open(m.interruption, msg))
+
+	case InterruptionMsg:
+		cmd = tea.Batch(
+			cmd,
+			m.pomodoro.do("interrupt", msg.Kind, msg.Note),
+			func() tea.Msg { return InitPomodoroMsg{} },
+		)
+
+	case SelectTaskMsg:
+		cmd = tea.Batch(
+			cmd,
+			m.pomodoro.do("task", msg.ID),
+			func() tea.Msg { return InitPomodoroMsg{} },
+		)
+
+	case SwitchProfileMsg:
+		// The pomodoro isn't the active submodel here so it's switched from the main model
+		cmd = tea.Batch(
+			cmd,
+			m.pomodoro.do("profile", msg.Name),
+			func() tea.Msg { return InitPomodoroMsg{} },
+		)
+
+	case APIRequestMsg:
+		response := daemonResponse{Ok: true}
+		if err := m.pomodoro.control(msg.Request.Cmd, msg.Request.Args); err != nil {
+			response = daemonResponse{Ok: false, Error: err.Error()}
+		}
+		state := m.pomodoro.state()
+		response.State = &state
+		msg.Reply <- response
+
+	case NotificationActionMsg:
+		cmd = tea.Batch(cmd, m.pomodoro.do(msg.Action), waitNotificationAction())
+
+	case HookFailedMsg:
+		cmd = tea.Batch(
+			cmd,
+			func() tea.Msg { return PopupMsg{Type: ErrorPopup, Content: msg.Content} },
+			waitHookFailure(),
+		)
+
+	case WebhookWarningMsg:
+		cmd = tea.Batch(
+			cmd,
+			func() tea.Msg { return PopupMsg{Type: WarningPopup, Content: msg.Content} },
+			waitWebhookWarning(),
+		)
+
+	case SuspendGapAnswerMsg:
+		m.pomodoro.countGap(msg.Elapsed)
+
+	// The daemon's messages are handled here so they aren't missed while a popup is shown
+	case DaemonStateMsg:
+		m.pomodoro.applyState(msg.State)
+		if msg.Subscribed && m.pomodoro.remote != nil {
+			cmd = tea.Batch(cmd, m.pomodoro.remote.wait())
+		}
+
+	case DaemonLostMsg:
+		// Going on locally from what the daemon saved before exiting
+		m.pomodoro.remote = nil
+		err := m.pomodoro.load()
+		content := "Lost the connection to the daemon, the timer runs in this terminal now."
+		if err != nil {
+			content += "\n" + err.Error()
+		}
+
+		cmd = tea.Batch(cmd, func() tea.Msg { return PopupMsg{Type: WarningPopup, Content: content} })
+		if Config.Notifications.Enabled {
+			cmd = tea.Batch(cmd, waitNotificationAction())
+		}
+		if len(Config.Webhooks) != 0 {
+			cmd = tea.Batch(cmd, waitWebhookWarning())
+		}
+		if m.api != nil {
+			if err := m.api.listen(); err != nil {
+				cmd = tea.Batch(cmd, func() tea.Msg { return PopupMsg{Type: ErrorPopup, Content: err.Error()} })
+			}
 		}
-		m.activeSubmodel = m.popup
 
 	case tea.InterruptMsg, tea.QuitMsg:
 		cmd = tea.Batch(cmd, tea.Quit)
@@ -81,16 +211,52 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 	}
 
+	// The events of whatever happened to the timer are written to the log
+	events := m.pomodoro.pending
+	written := len(events) != 0
+	runs := m.pomodoro.hooks(events)
+	if err := m.pomodoro.flush(); err != nil {
+		cmd = tea.Batch(cmd, func() tea.Msg { return PopupMsg{Type: ErrorPopup, Content: err.Error()} })
+	}
+	hooks.run(runs)
+	m.pomodoro.queueWebhooks(events)
+
+	if m.api != nil && written {
+		m.api.publish(events, m.pomodoro.eventState)
+	}
+
+	// The daemon writes the log when attached to one
+	_, daemonState := msg.(DaemonStateMsg)
+	if m.pomodoro.goals != nil && (written || daemonState) {
+		cmd = tea.Batch(cmd, m.pomodoro.goals.refresh())
+	}
+
 	return m, cmd
 }
 
+// Makes the submodel the active one and hands it the message opening it. NOTE: it's handed here
+// and not sent again, the view would be rendered once before the submodel got it
+func (m *MainModel) open(submodel Submodel, msg tea.Msg) tea.Cmd {
+	if m.activeSubmodel == submodel {
+		return nil // it was already given the message
+	}
+
+	m.activeSubmodel = submodel
+	return submodel.Update(msg)
+}
+
 func (m *MainModel) View() string {
 	var s string 
 
 	// Viewing the progressBar with a Remaining time bar
 	switch (m.activeSubmodel) {
-	case m.pomodoro:  s = m.pomodoro.Render()
-	case m.popup:     s = m.popup.Render()
+	case m.pomodoro:      s = m.pomodoro.Render()
+	case m.popup:         s = m.popup.Render()
+	case m.profiles:      s = m.profiles.Render()
+	case m.stats:         s = m.stats.Render()
+	case m.tasks:         s = m.tasks.Render()
+	case m.interruption:  s = m.interruption.Render()
+	case m.help:          s = m.help.Render()
 	}
 	
 	// Centering the view
@@ -117,16 +283,9 @@ func (m *MainModel) View() string {
 
 
 func main() {
-	// TODO: support command line arguments
-
-	p := tea.NewProgram(&MainModel{},
-		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(),
-	)
-
-	_, err := p.Run()
+	err := RunCLI(os.Args[1:])
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The TUI on the test's timer with the pomodoro view active
+func newTestMainModel(t *testing.T) *MainModel {
+	t.Helper()
+
+	pomodoro, _ := newTestModel(t, nil)
+
+	path := tasksPath
+	tasksPath = filepath.Join(t.TempDir(), "plumadoro_tasks.toml")
+	t.Cleanup(func() { tasksPath = path })
+
+	m := &MainModel{
+		pomodoro:     pomodoro,
+		popup:        &PopupModel{},
+		profiles:     &ProfilesModel{},
+		stats:        &StatsModel{clock: pomodoro.clock},
+		tasks:        &TasksModel{clock: pomodoro.clock},
+		interruption: &InterruptionModel{},
+		help:         &HelpModel{},
+	}
+	m.activeSubmodel = m.pomodoro
+
+	return m
+}
+
+// A view is opened as soon as it's the active one, before the first frame is rendered
+func TestOpenSubmodel(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     any
+		active  func(m *MainModel) Submodel
+		opened  func(m *MainModel) bool
+	}{
+		{
+			"popup", PopupMsg{Type: WarningPopup, Content: "warning"},
+			func(m *MainModel) Submodel { return m.popup },
+			func(m *MainModel) bool { return len(m.popup.popups) == 1 },
+		},
+		{
+			"profiles", OpenProfilesMsg{},
+			func(m *MainModel) Submodel { return m.profiles },
+			func(m *MainModel) bool { return len(m.profiles.names) != 0 },
+		},
+		{
+			"stats", OpenStatsMsg{},
+			func(m *MainModel) Submodel { return m.stats },
+			func(m *MainModel) bool { return m.stats.days != nil },
+		},
+		{
+			"tasks", OpenTasksMsg{},
+			func(m *MainModel) Submodel { return m.tasks },
+			func(m *MainModel) bool { return m.tasks.source != nil },
+		},
+		{
+			"help", OpenHelpMsg{},
+			func(m *MainModel) Submodel { return m.help },
+			func(m *MainModel) bool { return m.help.help.ShowAll },
+		},
+		{
+			"interruption", OpenInterruptionMsg{Kind: InternalInterruption},
+			func(m *MainModel) Submodel { return m.interruption },
+			func(m *MainModel) bool { return strings.Contains(m.View(), "Internal interruption") },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := newTestMainModel(t)
+
+			m.Update(test.msg)
+
+			if m.activeSubmodel != test.active(m) {
+				t.Fatalf("the %s view isn't the active one", test.name)
+			}
+			if !test.opened(m) {
+				t.Errorf("the %s view was rendered before being opened", test.name)
+			}
+		})
+	}
+}
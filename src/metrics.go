@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// /metrics of the HTTP API in Prometheus' text format, or in OpenMetrics' when the scraper asks for
+// it. The counters are totals of the whole log like the stats, so they survive restarts, and they're
+// kept up to date with the events written after it was read
+
+// The totals of the events since the start of the log
+type metricsTotals struct {
+	pomodoros  int // completed focus phases
+	breaks     int // completed breaks
+	skips      int
+	resets     int
+	focus      time.Duration
+	paused     time.Duration
+
+	last       pomodoroEvent // the time is counted from it to the next event
+	seen       bool
+}
+
+// Counts the events in the order they happened, the time is counted like summarize does. The times
+// are rounded like in the log so the totals don't change when they're read again from it, a counter
+// going down is a restart of the counter for Prometheus
+func (t *metricsTotals) add(events []pomodoroEvent) {
+	for _, e := range events {
+		e.remainingTime = e.remainingTime.Round(time.Second)
+		e.pausedTime    = e.pausedTime.Round(time.Second)
+
+		switch (e.event) {
+		case CompletedEvent:
+			if e.kind == Focus {
+				t.pomodoros++
+			} else {
+				t.breaks++
+			}
+		case SkippedEvent:
+			t.skips++
+		case ResetEvent:
+			t.resets++
+		}
+
+		if t.seen && samePhase(t.last, e) {
+			if e.kind == Focus {
+				t.focus += max(t.last.remainingTime - e.remainingTime, 0)
+			}
+			t.paused += max(e.pausedTime - t.last.pausedTime, 0)
+		}
+
+		t.last = e
+		t.seen = true
+	}
+}
+
+// The focus & paused time with the time since the last event, so they grow between the events. It's
+// in whole seconds never above what the next event adds once it's rounded
+func (t *metricsTotals) live(s PomodoroState) (time.Duration, time.Duration) {
+	focus, paused := t.focus, t.paused
+	if !t.seen || t.last.n != s.N || t.last.phase != s.Phase {
+		return focus, paused
+	}
+
+	seconds := func(f float64) time.Duration { return time.Duration(f) * time.Second }
+
+	if s.Kind == Focus.String() {
+		focus += max(t.last.remainingTime - seconds(math.Ceil(s.Remaining)), 0)
+	}
+	paused += max(seconds(math.Floor(s.Paused)) - t.last.pausedTime, 0)
+
+	return focus, paused
+}
+
+func boolMetric(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Writes the metrics, the counters' families don't have the _total suffix in OpenMetrics
+func writeMetrics(w io.Writer, t *metricsTotals, s PomodoroState, openMetrics bool) {
+	metric := func(name string, kind string, help string, samples ...string) {
+		family := name
+		if kind == "counter" && openMetrics {
+			family = strings.TrimSuffix(name, "_total")
+		}
+
+		fmt.Fprintf(w, "# HELP %s %s\n", family, help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", family, kind)
+		for _, sample := range samples {
+			fmt.Fprintf(w, "%s%s\n", name, sample)
+		}
+	}
+
+	metric("plumadoro_remaining_seconds", "gauge", "Remaining time of the current phase.",
+		fmt.Sprintf(" %g", s.Remaining))
+	metric("plumadoro_phase_duration_seconds", "gauge", "Duration of the current phase.",
+		fmt.Sprintf(" %g", s.Duration))
+
+	var phases []string
+	for _, kind := range phaseTypeNames {
+		phases = append(phases, fmt.Sprintf(`{kind="%s"} %d`, kind, boolMetric(s.Kind == kind)))
+	}
+	metric("plumadoro_phase", "gauge", "1 for the kind of the current phase.", phases...)
+
+	metric("plumadoro_paused", "gauge", "1 when the timer is paused.",
+		fmt.Sprintf(" %d", boolMetric(!s.Running)))
+
+	focus, paused := t.live(s)
+
+	metric("plumadoro_focus_sessions_total", "counter", "Completed focus phases.",
+		fmt.Sprintf(" %d", t.pomodoros))
+	metric("plumadoro_breaks_total", "counter", "Completed breaks.",
+		fmt.Sprintf(" %d", t.breaks))
+	metric("plumadoro_skips_total", "counter", "Skipped phases.",
+		fmt.Sprintf(" %d", t.skips))
+	metric("plumadoro_resets_total", "counter", "Reset phases.",
+		fmt.Sprintf(" %d", t.resets))
+	metric("plumadoro_focus_seconds_total", "counter", "Time spent focusing.",
+		fmt.Sprintf(" %g", focus.Seconds()))
+	metric("plumadoro_pause_seconds_total", "counter", "Time spent paused.",
+		fmt.Sprintf(" %g", paused.Seconds()))
+
+	if openMetrics {
+		fmt.Fprint(w, "# EOF\n")
+	}
+}
+
+func (a *apiServer) metrics(w http.ResponseWriter, r *http.Request) {
+	current, err := a.send(r.Context(), daemonRequest{Cmd: "status"})
+	if err != nil {
+		writeAPIResponse(w, http.StatusServiceUnavailable, daemonResponse{Error: err.Error()})
+		return
+	}
+
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	writeMetrics(w, &a.totals, *current.State, openMetrics)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The counters never go down, from the live values between the events to the totals of the events
+func TestMetricsMonotonic(t *testing.T) {
+	m, clock := newTestModel(t, func(c *ConfigT) { c.Autostart = true })
+	m.setRunning(true)
+
+	var totals metricsTotals
+	totals.add(m.pending)
+	m.pending = nil
+
+	var focus, paused time.Duration
+	check := func(when string, f time.Duration, p time.Duration) {
+		t.Helper()
+		if f < focus || p < paused {
+			t.Fatalf("%s at %s: focus %s paused %s, down from %s %s", when, clock.Now().Format(time.TimeOnly),
+				f, p, focus, paused)
+		}
+		focus, paused = f, p
+	}
+
+	// Uneven steps so the times aren't whole seconds, with a pause now and then
+	for step := 1; step <= 5000; step++ {
+		clock.advance(time.Millisecond * 1370)
+		m.tick(clock.Now())
+
+		if step % 97 == 0 {
+			m.control("toggle", nil)
+		}
+
+		// The events are counted before the state is scraped, like the API does
+		if len(m.pending) != 0 {
+			totals.add(m.pending)
+			m.pending = nil
+			check("add", totals.focus, totals.paused)
+		}
+
+		f, p := totals.live(m.state())
+		check("live", f, p)
+	}
+
+	if totals.pomodoros == 0 || totals.breaks == 0 || focus == 0 || paused == 0 {
+		t.Errorf("totals %+v, want some pomodoros, breaks, focus and paused time", totals)
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	totals := metricsTotals{pomodoros: 3, breaks: 2, skips: 1, focus: time.Minute * 75, paused: time.Minute * 4}
+	state := PomodoroState{Phase: "focus", Kind: "focus", Duration: 1500, Remaining: 600, Running: true}
+
+	t.Run("prometheus", func(t *testing.T) {
+		var b strings.Builder
+		writeMetrics(&b, &totals, state, false)
+		out := b.String()
+
+		for _, want := range []string{
+			"# TYPE plumadoro_focus_sessions_total counter\nplumadoro_focus_sessions_total 3\n",
+			"plumadoro_focus_seconds_total 4500\n",
+			`plumadoro_phase{kind="focus"} 1` + "\n",
+			`plumadoro_phase{kind="short_break"} 0` + "\n",
+			"plumadoro_paused 0\n",
+			"plumadoro_remaining_seconds 600\n",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%q isn't in the metrics:\n%s", want, out)
+			}
+		}
+		if strings.Contains(out, "# EOF") {
+			t.Error("the Prometheus format ends with # EOF")
+		}
+	})
+
+	t.Run("openmetrics", func(t *testing.T) {
+		var b strings.Builder
+		writeMetrics(&b, &totals, state, true)
+		out := b.String()
+
+		// The families of the counters don't have the _total suffix, their samples do
+		for _, want := range []string{
+			"# TYPE plumadoro_focus_sessions counter\nplumadoro_focus_sessions_total 3\n",
+			"# HELP plumadoro_pause_seconds Time spent paused.\n",
+			"# TYPE plumadoro_remaining_seconds gauge\n",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%q isn't in the metrics:\n%s", want, out)
+			}
+		}
+		if strings.Contains(out, "# TYPE plumadoro_focus_sessions_total") {
+			t.Error("a counter's family has the _total suffix")
+		}
+		if !strings.HasSuffix(out, "\n# EOF\n") {
+			t.Error("the metrics don't end with # EOF")
+		}
+	})
+}
+
+// /metrics counts the log read when the API starts listening with the state of the timer
+func TestMetricsEndpoint(t *testing.T) {
+	m, _ := newTestModel(t, nil)
+	Config.HTTP.Listen = "127.0.0.1:0"
+
+	events := []pomodoroEvent{
+		testEvent(0, PhaseStartedEvent, 1, time.Minute * 25, 0, true),
+		testEvent(25, CompletedEvent, 1, 0, 0, true),
+	}
+	if err := appendEvents(events); err != nil {
+		t.Fatal(err)
+	}
+
+	a := newAPIServer(func(ctx context.Context, request daemonRequest) (daemonResponse, error) {
+		state := m.state()
+		return daemonResponse{Ok: true, State: &state}, nil
+	})
+	if err := a.listen(); err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	for _, accept := range []string{"text/plain", "application/openmetrics-text; version=1.0.0"} {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		r.Host = "localhost"
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+
+		a.handler().ServeHTTP(w, r)
+
+		openMetrics := strings.HasPrefix(accept, "application/openmetrics-text")
+		contentType := w.Header().Get("Content-Type")
+		if w.Code != http.StatusOK || strings.HasPrefix(contentType, "application/openmetrics-text") != openMetrics {
+			t.Fatalf("Accept %s: %d %s", accept, w.Code, contentType)
+		}
+
+		body := w.Body.String()
+		if !strings.Contains(body, "plumadoro_focus_sessions_total 1\n") || !strings.Contains(body, "plumadoro_focus_seconds_total 1500\n") {
+			t.Errorf("Accept %s: the log isn't counted:\n%s", accept, body)
+		}
+		if strings.HasSuffix(body, "# EOF\n") != openMetrics {
+			t.Errorf("Accept %s: # EOF at the end is %v, want %v", accept, !openMetrics, openMetrics)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Desktop notifications through the freedesktop notification server on the session bus
+// (https://specifications.freedesktop.org/notification-spec), the buttons of a notification
+// are timer actions sent back to the model. Without a session bus or a notification server
+// nothing is shown
+
+const (
+	notificationsName       = "org.freedesktop.Notifications"
+	notificationsPath       = dbus.ObjectPath("/org/freedesktop/Notifications")
+	notificationsInterface  = "org.freedesktop.Notifications"
+)
+
+// The urgencies of the spec by their names in the config
+var notificationUrgencies = map[string]byte{"low": 0, "normal": 1, "critical": 2}
+
+// Sent to the TUI when a button of a notification is clicked, it's an action of control()
+type NotificationActionMsg struct {
+	Action string
+}
+
+type notifier struct {
+	mu       sync.Mutex
+	sending  sync.Mutex // the notifications are sent one after the other
+	conn     *dbus.Conn // nil when there is no notification server
+	buttons  bool // the server shows the actions as buttons
+	id       uint32 // of the last notification, the next one replaces it
+	ready    chan struct{} // closed once connected or given up
+	actions  chan string // the actions of the clicked buttons
+}
+
+var (
+	notifications     = newNotifier()
+	notificationsOnce sync.Once
+)
+
+func newNotifier() *notifier {
+	return &notifier{
+		ready:   make(chan struct{}),
+		actions: make(chan string, 8),
+	}
+}
+
+// Connects to the session bus the first time it's called
+func getNotifier() *notifier {
+	notificationsOnce.Do(func() { go notifications.connect(dbus.ConnectSessionBus) })
+	return notifications
+}
+
+// Checks there is a notification server and listens to its clicked buttons, connect is a
+// parameter so another bus can stand in for the session bus
+func (n *notifier) connect(connect func(opts ...dbus.ConnOption) (*dbus.Conn, error)) {
+	defer close(n.ready)
+
+	conn, err := connect()
+	if err != nil {
+		return // no session bus e.g. a server or a TTY
+	}
+
+	var capabilities []string
+	server := conn.Object(notificationsName, notificationsPath)
+	if err := server.Call(notificationsInterface + ".GetCapabilities", 0).Store(&capabilities); err != nil {
+		conn.Close()
+		return
+	}
+
+	err = conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(notificationsPath),
+		dbus.WithMatchInterface(notificationsInterface),
+		dbus.WithMatchMember("ActionInvoked"),
+	)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	signals := make(chan *dbus.Signal, 8)
+	conn.Signal(signals)
+
+	n.mu.Lock()
+	n.conn    = conn
+	n.buttons = slices.Contains(capabilities, "actions")
+	n.mu.Unlock()
+
+	go n.listen(signals)
+}
+
+// Passes on the actions of the buttons of plumadoro's notifications
+func (n *notifier) listen(signals chan *dbus.Signal) {
+	for signal := range signals {
+		var id uint32
+		var action string
+		if signal.Name != notificationsInterface + ".ActionInvoked" || dbus.Store(signal.Body, &id, &action) != nil {
+			continue
+		}
+
+		n.mu.Lock()
+		ours := id == n.id
+		n.mu.Unlock()
+
+		// "default" is a click on the notification itself
+		if !ours || action == "default" {
+			continue
+		}
+
+		select {
+		case n.actions <- action:
+		default: // nobody is taking them
+		}
+	}
+}
+
+// Shows a notification replacing the last one, actions are pairs of an action and its label.
+// It doesn't wait for the server
+func (n *notifier) notify(summary string, body string, urgency byte, actions []string) {
+	go func() {
+		<-n.ready
+
+		n.sending.Lock()
+		defer n.sending.Unlock()
+
+		n.mu.Lock()
+		conn, replaces, buttons := n.conn, n.id, n.buttons
+		n.mu.Unlock()
+
+		if conn == nil {
+			return
+		}
+		if !buttons || actions == nil {
+			actions = []string{}
+		}
+
+		hints := map[string]dbus.Variant{"urgency": dbus.MakeVariant(urgency)}
+
+		var id uint32
+		err := conn.Object(notificationsName, notificationsPath).Call(
+			notificationsInterface + ".Notify", 0,
+			"plumadoro", replaces, "", summary, body, actions, hints, int32(-1),
+		).Store(&id)
+		if err != nil {
+			return
+		}
+
+		n.mu.Lock()
+		n.id = id
+		n.mu.Unlock()
+	}()
+}
+
+// Waits for a button of a notification to be clicked
+func waitNotificationAction() tea.Cmd {
+	actions := getNotifier().actions
+	return func() tea.Msg { return NotificationActionMsg{Action: <-actions} }
+}
+
+// Formats a duration like 5m or 1h30m
+func shortDuration(d time.Duration) string {
+	s := d.Round(time.Second).String()
+	if d % time.Minute == 0 {
+		s = s[:len(s) - 2] // 0s
+	}
+	if d % time.Hour == 0 && d != 0 {
+		s = s[:len(s) - 2] // 0m
+	}
+
+	return s
+}
+
+// Notifies the start of the current phase, alarm is whether an alarm is ringing to snooze
+func (m *PomodoroModel) notifyPhase(alarm bool) {
+	if !Config.Notifications.Enabled || m.remote != nil {
+		return
+	}
+
+	phase := m.getPhase()
+
+	urgency := Config.Notifications.BreakUrgency
+	if phase.kind == Focus {
+		urgency = Config.Notifications.FocusUrgency
+	}
+
+	var actions []string
+	if !m.running {
+		actions = append(actions, "start", "Start")
+	}
+	if Config.Skipping {
+		actions = append(actions, "skip", "Skip")
+	}
+	if alarm {
+		actions = append(actions, "snooze", "Snooze " + shortDuration(Config.Alarm.Snooze))
+	}
+
+	// NOTE: not getPhaseMsg(), the pause message would be the title of every phase not started yet
+	getNotifier().notify(
+		phase.msg,
+		fmt.Sprintf("%s for %s", phase.name, shortDuration(phase.duration)),
+		notificationUrgencies[urgency],
+		actions,
+	)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// A notification server recording the notifications it's sent
+type fakeNotifications struct {
+	notified chan fakeNotification
+}
+
+type fakeNotification struct {
+	replaces  uint32
+	summary   string
+	body      string
+	actions   []string
+	urgency   byte
+}
+
+func (f *fakeNotifications) GetCapabilities() ([]string, *dbus.Error) {
+	return []string{"actions", "body"}, nil
+}
+
+func (f *fakeNotifications) Notify(app string, replaces uint32, icon string, summary string, body string,
+	actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	var urgency byte
+	hints["urgency"].Store(&urgency)
+
+	f.notified <- fakeNotification{replaces, summary, body, actions, urgency}
+	return 7, nil
+}
+
+const testBusConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// Starts a bus of the test and returns its address, the test is skipped without dbus-daemon
+func startTestBus(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon isn't installed")
+	}
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(config, []byte(fmt.Sprintf(testBusConfig, filepath.Join(dir, "bus"))), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	daemon := exec.Command("dbus-daemon", "--config-file=" + config, "--nofork", "--print-address")
+	stdout, err := daemon.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := daemon.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		daemon.Process.Kill()
+		daemon.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading the address of the bus: %v", err)
+	}
+
+	return strings.TrimSpace(address)
+}
+
+// Puts a notifier connected to a bus of the test with a fake notification server in place of the
+// session bus's
+func useTestNotifier(t *testing.T) (*fakeNotifications, *dbus.Conn) {
+	t.Helper()
+
+	address := startTestBus(t)
+
+	serverConn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { serverConn.Close() })
+
+	server := &fakeNotifications{notified: make(chan fakeNotification, 8)}
+	if err := serverConn.Export(server, notificationsPath, notificationsInterface); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := serverConn.RequestName(notificationsName, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("owning %s: %v", notificationsName, err)
+	}
+
+	// NOTE: the once is used up so getNotifier() doesn't connect to the session bus
+	notificationsOnce.Do(func() {})
+	previous := notifications
+	notifications = newNotifier()
+	t.Cleanup(func() {
+		notifications.mu.Lock()
+		if notifications.conn != nil {
+			notifications.conn.Close()
+		}
+		notifications.mu.Unlock()
+		notifications = previous
+	})
+
+	notifications.connect(func(opts ...dbus.ConnOption) (*dbus.Conn, error) {
+		return dbus.Connect(address, opts...)
+	})
+
+	return server, serverConn
+}
+
+func TestNotifyPhase(t *testing.T) {
+	m, _ := newTestModel(t, nil)
+	server, serverConn := useTestNotifier(t)
+	Config.Notifications.Enabled = true
+
+	m.notifyPhase(true)
+
+	var got fakeNotification
+	select {
+	case got = <-server.notified:
+	case <-time.After(time.Second * 5):
+		t.Fatal("no notification was sent")
+	}
+
+	phase := m.getPhase()
+	want := fakeNotification{
+		summary: phase.msg,
+		body:    "focus for 25m",
+		actions: []string{"start", "Start", "skip", "Skip", "snooze", "Snooze " + shortDuration(Config.Alarm.Snooze)},
+		urgency: notificationUrgencies[Config.Notifications.FocusUrgency],
+	}
+	if got.replaces != want.replaces || got.summary != want.summary || got.body != want.body ||
+		!slices.Equal(got.actions, want.actions) || got.urgency != want.urgency {
+		t.Errorf("Notify(%+v), want %+v", got, want)
+	}
+
+	// The id of the notification is saved once the server answered
+	for deadline := time.Now().Add(time.Second * 5); ; time.Sleep(time.Millisecond * 10) {
+		notifications.mu.Lock()
+		id := notifications.id
+		notifications.mu.Unlock()
+
+		if id == 7 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the id of the notification wasn't saved")
+		}
+	}
+
+	// The clicks on the notification itself and on other applications' notifications are ignored
+	emit := func(id uint32, action string) {
+		if err := serverConn.Emit(notificationsPath, notificationsInterface + ".ActionInvoked", id, action); err != nil {
+			t.Fatal(err)
+		}
+	}
+	emit(7, "default")
+	emit(3, "start")
+	emit(7, "skip")
+
+	msgs := make(chan any, 1)
+	go func() { msgs <- waitNotificationAction()() }()
+
+	select {
+	case msg := <-msgs:
+		if msg != (NotificationActionMsg{Action: "skip"}) {
+			t.Errorf("waitNotificationAction() = %#v, want the skip action", msg)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("the clicked action wasn't received")
+	}
+}
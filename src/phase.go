@@ -0,0 +1,111 @@
+package main
+
+import (
+	"time"
+)
+
+// A phase of the sequence the timer loops over, it's built from the config by buildPhases()
+type phase struct {
+	name      string
+	kind      phaseType // what the phase counts as in the stats (and its default look)
+	duration  time.Duration
+	color     string
+	msg       string
+}
+
+var phaseTypeNames = []string{"focus", "short_break", "long_break"}
+
+func (t phaseType) String() string {
+	if int(t) >= len(phaseTypeNames) {
+		return "unknown"
+	}
+
+	return phaseTypeNames[t]
+}
+
+func parsePhaseType(s string) (phaseType, bool) {
+	for i, name := range phaseTypeNames {
+		if name == s {
+			return phaseType(i), true
+		}
+	}
+
+	return Focus, false
+}
+
+// The look and duration a phase gets when the config doesn't say otherwise
+func defaultPhase(kind phaseType) phase {
+	p := phase{name: kind.String(), kind: kind}
+
+	switch (kind) {
+	case Focus:
+		p.duration = Config.Durations.Focus
+		p.color    = Config.ProgressBar.FocusColor
+		p.msg      = Config.ProgressBar.FocusMsg
+	case ShortBreak:
+		p.duration = Config.Durations.ShortBreak
+		p.color    = Config.ProgressBar.ShortBreakColor
+		p.msg      = Config.ProgressBar.ShortBreakMsg
+	case LongBreak:
+		p.duration = Config.Durations.LongBreak
+		p.color    = Config.ProgressBar.LongBreakColor
+		p.msg      = Config.ProgressBar.LongBreakMsg
+	}
+
+	return p
+}
+
+// Builds the phases the timer loops over, it's either the user's [[sequence]] or the classic
+// pomodoro cycle (focus and short breaks then a long break at the end)
+func buildPhases() []phase {
+	var phases []phase
+
+	if len(Config.Sequence) == 0 {
+		sessions := int(Config.Cycle.SessionsBeforeLongBreak)
+
+		for i := 1; i <= sessions; i++ {
+			phases = append(phases, defaultPhase(Focus))
+
+			if i == sessions {
+				phases = append(phases, defaultPhase(LongBreak))
+			} else {
+				phases = append(phases, defaultPhase(ShortBreak))
+			}
+		}
+
+		return phases
+	}
+
+	// NOTE: the sequence was validated in LoadConfig so the kinds are known
+	for _, c := range Config.Sequence {
+		kind, _ := parsePhaseType(c.Kind)
+		p := defaultPhase(kind)
+
+		p.name     = c.Name
+		p.duration = c.Duration
+
+		if c.Color != "" {
+			p.color = c.Color
+		}
+
+		if c.Msg != "" {
+			p.msg = c.Msg
+		}
+
+		phases = append(phases, p)
+	}
+
+	return phases
+}
+
+// Counts the focus phases in the given phases
+func countFocus(phases []phase) int {
+	count := 0
+	for _, p := range phases {
+		if p.kind == Focus {
+			count++
+		}
+	}
+
+	return count
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"math"
+	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -15,20 +17,53 @@ type InitPomodoroMsg struct{}
 
 type PomodoroTickMsg time.Time
 
+// The answer to the popup asking what to do with a suspend gap
+type SuspendGapAnswerMsg struct {
+	Elapsed bool // count the gap as elapsed time instead of paused time
+}
+
 type phaseType byte
 
 type PomodoroModel struct {
-	remainingTime    time.Duration
+	remainingTime    time.Duration // NOTE: it's computed from the deadline while running
 	pausedTime       time.Duration
-	phaseType        phaseType
 	running          bool
-	n                uint8 
+	n                uint64 // NOTE: one based index of the phases since the start of the day
+
+	// The time is taken from the wall clock so the timer doesn't drift when ticks are late
+	deadline         time.Time // when the phase ends, only valid while running
+	lastTick         time.Time
+	gap              time.Duration // the last suspend gap, it's counted as paused until answered
 
 	// Configurable
-	phasesDurations  map[phaseType]time.Duration
+	phases           []phase
 	progressBar      progress.Model
+
+	clock            Clock
+	alarm            func(kind phaseType) bool // plays the alarm of a phase ending, PlayAlarm but in the tests
+	remote           *daemonClient // the daemon the TUI is attached to, nil when running locally
+	pending          []pomodoroEvent // events waiting to be written to the log
+	goals            *goalsTracker // nil when no goal is set
+	task             taskItem // the task being worked on, its ID is empty for no task
+
+	// The interruptions of the current phase
+	internalInterruptions  int
+	externalInterruptions  int
+
+	muted            bool // the ambient sound is muted
+	ambient          string // the ambient sound being played, empty until one is played
+	quitted          bool // the app_quit event was logged
 }
 
+var (
+	ErrPausingNotAllowed   = errors.New("Pausing phases is unallowed in your config")
+	ErrSkippingNotAllowed  = errors.New("Skipping phases is unallowed in your config")
+	ErrUnknownAction       = errors.New("Unknown action")
+	ErrNoAlarm             = errors.New("No alarm to snooze")
+	ErrUnknownPhase        = errors.New("No phase with this name or kind")
+)
+
+// The kinds of phases, user defined phases are one of them too
 const (
 	Focus phaseType = iota
 	ShortBreak
@@ -36,134 +71,328 @@ const (
 )
 
 
-func (m *PomodoroModel) getPhaseMsg() string {
-	var msg string
+// The current phase, the phases sequence loops so n keeps increasing across cycles
+func (m *PomodoroModel) getPhase() phase {
+	return m.phases[(m.n - 1) % uint64(len(m.phases))]
+}
 
-	if m.running {
-		switch (m.phaseType) {
-		case Focus:        msg = Config.ProgressBar.FocusMsg
-		case ShortBreak:   msg = Config.ProgressBar.ShortBreakMsg
-		case LongBreak:    msg = Config.ProgressBar.LongBreakMsg
-		}
-	} else {
-		msg = Config.ProgressBar.PauseMsg
+func (m *PomodoroModel) getPhaseMsg() string {
+	if !m.running {
+		return Config.ProgressBar.PauseMsg
 	}
 
-	return msg
+	return m.getPhase().msg
 }
 
-// HACK: IDK WTH is this
-func (m *PomodoroModel) getPhaseColor() string { 
-	var progressColor string
-	switch (m.phaseType) {
-		case Focus:       progressColor = Config.ProgressBar.FocusColor
-		case ShortBreak:  progressColor = Config.ProgressBar.ShortBreakColor
-		case LongBreak:   progressColor = Config.ProgressBar.LongBreakColor
-	}
+func (m *PomodoroModel) getPhaseColor() string {
+	return m.getPhase().color
+}
+
+// The one based index of the current focus session (breaks share the index of the focus before them)
+func (m *PomodoroModel) getSession() int {
+	return m.sessionOf(m.n)
+}
+
+// The one based index of the focus session of the n-th phase
+func (m *PomodoroModel) sessionOf(n uint64) int {
+	cycles := int((n - 1) / uint64(len(m.phases)))
+	return cycles * countFocus(m.phases) + m.cyclePositionOf(n)
+}
+
+// The one based position of the current focus session inside its cycle
+func (m *PomodoroModel) getCyclePosition() int {
+	return m.cyclePositionOf(m.n)
+}
 
-	return progressColor
+func (m *PomodoroModel) cyclePositionOf(n uint64) int {
+	position := int((n - 1) % uint64(len(m.phases)))
+	return countFocus(m.phases[:position + 1])
 }
 
 func (m *PomodoroModel) getProgress() float64 {
 	return float64(m.remainingTime) /
-		   float64(m.phasesDurations[m.phaseType])
+		   float64(m.getPhase().duration)
 }
 
 
+func NewPomodoroModel(clock Clock) *PomodoroModel {
+	return &PomodoroModel{clock: clock, alarm: PlayAlarm}
+}
+
+// Starts over from the first phase of the sequence
+func (m *PomodoroModel) startOver() {
+	*m = PomodoroModel {
+		clock:         m.clock,
+		alarm:         m.alarm,
+		remote:        m.remote,
+		goals:         m.goals,
+		task:          m.task,
+		muted:         m.muted,
+		ambient:       m.ambient,
+		pausedTime:    time.Duration(0),
+		running:       false,
+		n:             1, // NOTE: the index of phases is one based
+
+		phases:        buildPhases(),
+	}
+	m.remainingTime = m.getPhase().duration
+	m.progressBar   = progress.New(progress.WithSolidFill(m.getPhaseColor()))
+	m.setRunning(Config.Autostart)
+	m.emit(PhaseStartedEvent)
+}
+
+// Restores the last state from the log or starts over if it can't be restored
+func (m *PomodoroModel) load() error {
+	err := m.restore()
+	if err != nil {
+		m.startOver()
+	}
+
+	return err
+}
+
 func (m *PomodoroModel) Init() tea.Cmd {
-	if err := m.restore(); err != nil {
-		*m = PomodoroModel {
-			remainingTime: Config.Durations.Focus,
-			pausedTime:    time.Duration(0),
-			phaseType:     Focus,
-			running:       Config.Autostart,
-			n:             1, // NOTE: the index of phases is one based
-
-			phasesDurations: map[phaseType]time.Duration{
-				Focus:      Config.Durations.Focus,
-				ShortBreak: Config.Durations.ShortBreak,
-				LongBreak:  Config.Durations.LongBreak,
-			},
-			progressBar:     progress.New(
-				progress.WithSolidFill(Config.ProgressBar.FocusColor)),
-		}
+	// The state comes from the daemon when attached to one
+	if m.remote != nil {
+		m.startOver()
+		return tea.Batch(
+			m.remote.wait(),
+			func() tea.Msg { return InitPomodoroMsg{} },
+		)
+	}
 
+	if err := m.load(); err != nil {
 		return func() tea.Msg { return PopupMsg{Type: ErrorPopup, Content: err.Error() } }
 	}
 
 	return func() tea.Msg { return InitPomodoroMsg{} } 
 }
 
+// Applies an action to the timer, it's what the keys and the clients of the daemon do
+func (m *PomodoroModel) control(action string, args []string) error {
+	running := m.running
+
+	switch (action) {
+	case "start":
+		if len(args) > 1 {
+			return fmt.Errorf("%w: start takes the name or kind of a phase to skip to", ErrUnknownAction)
+		}
+
+		// Starting another phase than the current one skips to it
+		if len(args) == 1 && args[0] != m.getPhase().name && args[0] != m.getPhase().kind.String() {
+			return m.jump(args[0])
+		}
+		m.setRunning(true)
+
+	case "pause":
+		if !Config.Pausing {
+			return ErrPausingNotAllowed
+		}
+		m.setRunning(false)
+
+	case "toggle":
+		if !Config.Pausing && m.running {
+			return ErrPausingNotAllowed
+		}
+		m.toggle()
+
+	case "skip":
+		if !Config.Skipping {
+			return ErrSkippingNotAllowed
+		}
+		m.emit(SkippedEvent)
+		m.next()
+		return nil
+
+	case "reset":
+		m.reset()
+		m.emit(ResetEvent)
+		return nil
+
+	case "profile":
+		if len(args) != 1 {
+			return fmt.Errorf("%w: profile takes the name of the profile", ErrUnknownAction)
+		}
+		return m.switchProfile(args[0])
+
+	case "interrupt":
+		if len(args) == 0 {
+			return fmt.Errorf("%w: interrupt takes \"internal\" or \"external\" and a note", ErrUnknownAction)
+		}
+		return m.interrupt(args[0], strings.Join(args[1:], " "))
+
+	case "task":
+		if len(args) > 1 {
+			return fmt.Errorf("%w: task takes the ID of the task", ErrUnknownAction)
+		}
+
+		id := strings.Join(args, "")
+		if id == m.task.ID {
+			return nil
+		}
+
+		if err := m.setTask(id); err != nil {
+			return err
+		}
+		m.emit(TaskSwitchedEvent)
+		return nil
+
+	case "silence":
+		getAudio().stop()
+		return nil
+
+	case "snooze":
+		if !getAudio().snooze(Config.Alarm.Snooze) {
+			return ErrNoAlarm
+		}
+		return nil
+
+	case "mute":
+		m.muted = !m.muted
+		m.syncAmbient()
+		return nil
+
+	case "status":
+		// Nothing to do, the state is sent back anyway
+
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownAction, action)
+	}
+
+	if running && !m.running {
+		m.emit(PausedEvent)
+	} else if !running && m.running {
+		m.emit(ResumedEvent)
+	}
+
+	return nil
+}
+
+// Does an action from the TUI, it's sent to the daemon instead when attached to one
+func (m *PomodoroModel) do(action string, args ...string) tea.Cmd {
+	if m.remote != nil {
+		return m.remote.command(action, args...)
+	}
+
+	if err := m.control(action, args); err != nil {
+		return func() tea.Msg { return PopupMsg{Type: WarningPopup, Content: err.Error()} }
+	}
+
+	return nil
+}
+
+// Stops the TUI, the daemon logs its own state when attached to one
+func (m *PomodoroModel) quit() tea.Cmd {
+	m.logQuit()
+	return tea.Quit
+}
+
+// Logs the app_quit event once, however the TUI stops
+func (m *PomodoroModel) logQuit() {
+	if m.quitted {
+		return
+	}
+	m.quitted = true
+
+	// NOTE: the hooks are waited for once the program stopped, see runStart
+	m.emit(AppQuitEvent)
+	hooks.run(m.hooks(m.pending))
+	m.flush()
+}
+
 func (m *PomodoroModel) Update(msg tea.Msg) tea.Cmd { 
 	var cmd tea.Cmd = nil
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		// TODO: make the key bindings customizable
-		switch msg.String() {
-		case "ctrl+c", "q", "esc":
-			m.save()
-			cmd = tea.Quit
-
-		case " ":
-			if !Config.Pausing && m.running {
-				cmd = func() tea.Msg { return PopupMsg{
-					Type: WarningPopup,
-					Content: "Pausing phases is unallowed in your config",
-				}}
-			} else {
-				m.toggle()
-			}
+		keys := newKeyMap()
 
-		case "ctrl+r":
-			m.reset()
+		switch {
+		case key.Matches(msg, keys.Quit):
+			cmd = m.quit()
 
-		case "ctrl+s":
-			if !Config.Skipping {
-				cmd = func() tea.Msg { return PopupMsg{
-					Type: WarningPopup,
-					Content: "Skipping phases is unallowed in your config",
-				}}
-			} else {
-				m.next()
-			}
-	}
+		case key.Matches(msg, keys.Toggle):
+			cmd = m.do("toggle")
+
+		case key.Matches(msg, keys.Reset):
+			cmd = m.do("reset")
+
+		case key.Matches(msg, keys.Skip):
+			cmd = m.do("skip")
+
+		case key.Matches(msg, keys.Profiles):
+			cmd = func() tea.Msg { return OpenProfilesMsg{} }
+
+		case key.Matches(msg, keys.Stats):
+			cmd = func() tea.Msg { return OpenStatsMsg{} }
+
+		case key.Matches(msg, keys.Tasks):
+			cmd = func() tea.Msg { return OpenTasksMsg{} }
+
+		case key.Matches(msg, keys.Silence):
+			cmd = m.do("silence")
+
+		case key.Matches(msg, keys.Snooze):
+			cmd = m.do("snooze")
+
+		case key.Matches(msg, keys.Mute):
+			cmd = m.do("mute")
+
+		case key.Matches(msg, keys.Help):
+			cmd = func() tea.Msg { return OpenHelpMsg{} }
+
+		case key.Matches(msg, keys.InternalInterruption):
+			cmd = m.openInterruption(InternalInterruption)
+
+		case key.Matches(msg, keys.ExternalInterruption):
+			cmd = m.openInterruption(ExternalInterruption)
+		}
 
 	case tea.InterruptMsg, tea.QuitMsg:
-		m.save()
-		cmd = tea.Quit
+		cmd = m.quit()
 
 	case tea.WindowSizeMsg:
 		m.resizeProgressBar(msg.Width)
 
 	case PomodoroTickMsg:
-		m.tick(Config.TickDuration)
 		cmd = tickPomodoroEvery()
 
-		if !m.running {
-			if m.pausedTime >= Config.MaxPauseDuration {
-				cmd = func() tea.Msg { return PopupMsg{
-					Type: WarningPopup,
-					Content: "You have passed your maximum pause time per phase, resetting the phase.",
-				}}
-				m.reset()
+		// NOTE: the time of the message is bubbletea's, the timer's one is the clock's
+		gap := m.tick(m.clock.Now())
+		if gap != 0 {
+			cmd = func() tea.Msg { return PopupMsg{
+				Type: WarningPopup,
+				Content: fmt.Sprintf("The timer was suspended for %s, how should it be counted?", gap.Round(time.Second)),
+				Actions: []PopupAction{
+					{Key: "p", Label: "As paused time",  Msg: SuspendGapAnswerMsg{Elapsed: false}},
+					{Key: "e", Label: "As elapsed time", Msg: SuspendGapAnswerMsg{Elapsed: true}},
+				},
+			}}
+		}
+
+		if m.remote == nil && m.pauseLimitReached() {
+			// NOTE: the gap's popup is kept, it still has to be answered
+			popup := func() tea.Msg { return PopupMsg{
+				Type: WarningPopup,
+				Content: "You have passed your maximum pause time per phase, resetting the phase.",
+			}}
+			if gap != 0 {
+				cmd = tea.Batch(cmd, popup)
+			} else {
+				cmd = popup
 			}
+			m.reset()
+			m.emit(ResetEvent)
 		}
 
 	case InitPomodoroMsg:
+		// The ticks stop while other submodels are active, that's not a suspend gap
+		m.lastTick = m.clock.Now()
+
 		cmd = tea.Batch(
 			tickPomodoroEvery(),
-			tickLogEvery(),
 			tea.WindowSize(),
 		)
-
-	case LogTickMsg:
-		err := m.save()
-		if err != nil {
-			cmd = func() tea.Msg { return PopupMsg{Type: ErrorPopup, Content: err.Error()} }
-		} else {
-			cmd = tickLogEvery()
-		}
 	}
 
 	return cmd
@@ -175,78 +404,244 @@ func (m *PomodoroModel) Render() string {
 		phaseColor = Config.ProgressBar.PauseColor
 	}
 
+	lines := []string{
+		lipgloss.NewStyle().Foreground(lipgloss.Color(phaseColor)).Render(m.getPhaseMsg()), 
+		// make this configurable ^
+	}
+
+	if m.task.Title != "" {
+		lines = append(lines, "Task: " + m.task.Title)
+	}
+
+	lines = append(lines,
+		"",
+		m.progressBar.ViewAs(m.getProgress()),
+		"",
+		fmt.Sprintf("Remaining: %02d:%02d | #%d (%d/%d)",
+			int(m.remainingTime.Minutes()),
+			int(m.remainingTime.Seconds()) % 60,
+			m.getSession(),
+			m.getCyclePosition(),
+			countFocus(m.phases)),
+	)
+
+	if m.internalInterruptions != 0 || m.externalInterruptions != 0 {
+		lines = append(lines, fmt.Sprintf("Interruptions: %d internal, %d external",
+			m.internalInterruptions, m.externalInterruptions))
+	}
+
+	if m.muted {
+		lines = append(lines, "Ambient sound muted")
+	}
+
+	// The goals' progress under the timer
+	if m.goals != nil {
+		lines = append(lines, "", m.goals.Render(m.progressBar.Width))
+	}
+
 	s := GetBorderStyle(phaseColor).Render(
-		lipgloss.JoinVertical(
-			lipgloss.Center,
-			lipgloss.NewStyle().Foreground(lipgloss.Color(phaseColor)).Render(m.getPhaseMsg()), 
-			// make this configurable ^
-			"",
-			m.progressBar.ViewAs(m.getProgress()),
-			"",
-			fmt.Sprintf("Remaining: %02d:%02d | #%d",
-				int(m.remainingTime.Minutes()),
-				int(m.remainingTime.Seconds()) % 60,
-				int(math.Ceil(float64(m.n) / 2.0))),
-			),
-		)
+		lipgloss.JoinVertical(lipgloss.Center, lines...),
+	)
 
 	return s
 }
 
 
-func (m *PomodoroModel) toggle() {
-	if m.running == false {
-		m.running = true
-	} else {
-		m.running = false
+// Looks the task up in the task source, an empty ID is for working without a task
+func (m *PomodoroModel) setTask(id string) error {
+	if id == "" {
+		m.task = taskItem{}
+		return nil
+	}
+
+	task, err := findTask(id)
+	if err != nil {
+		return err
 	}
+
+	m.task = task
+	return nil
+}
+
+// Starts or stops the clock of the current phase
+func (m *PomodoroModel) setRunning(running bool) {
+	now := m.clock.Now().Round(0) // the deadline is wall clock time, see systemClock
+
+	if running && !m.running {
+		m.deadline = now.Add(m.remainingTime)
+	} else if !running && m.running {
+		m.remainingTime = m.deadline.Sub(now)
+	}
+
+	m.running = running
+	m.syncAmbient()
+}
+
+func (m *PomodoroModel) toggle() {
+	m.setRunning(!m.running)
+}
+
+// The longest time between two ticks that isn't considered a suspend (ctrl+z, sleeping, ...)
+func suspendThreshold() time.Duration {
+	return max(time.Second*10, Config.TickDuration*3)
 }
 
-func (m *PomodoroModel) tick(d time.Duration) {
+// Updates the remaining time from the clock, it returns the suspend gap if one was detected
+func (m *PomodoroModel) tick(now time.Time) time.Duration {
+	var gap time.Duration
+	now = now.Round(0) // compared with the wall clock, see systemClock
+
+	elapsed := now.Sub(m.lastTick)
+	if m.lastTick.IsZero() || elapsed < 0 {
+		elapsed = 0
+	}
+	m.lastTick = now
+
+	// The daemon moves to the next phase and handles the gaps, it's only displayed here
+	if m.remote != nil {
+		if m.running {
+			m.remainingTime = max(m.deadline.Sub(now), 0)
+		} else {
+			m.pausedTime += elapsed
+		}
+		return 0
+	}
+
+	// While paused the gap is paused time anyway so it's only handled while running
+	if m.running && elapsed > suspendThreshold() {
+		gap = elapsed - Config.TickDuration
+		m.deadline = m.deadline.Add(gap)
+		m.pausedTime += gap
+		m.gap = gap
+	}
+
 	if m.running {
-		m.remainingTime -= d
+		m.remainingTime = m.deadline.Sub(now)
 	} else {
-		m.pausedTime += d
+		m.pausedTime += elapsed
 	}
 
 	if m.remainingTime <= time.Duration(0) {
+		m.emit(CompletedEvent)
 		m.next()
 	}
+
+	return gap
+}
+
+func (m *PomodoroModel) pauseLimitReached() bool {
+	return !m.running && m.pausedTime >= Config.MaxPauseDuration
+}
+
+// Counts the last suspend gap as elapsed time instead of paused time
+func (m *PomodoroModel) countGap(elapsed bool) {
+	if elapsed && m.gap != 0 {
+		m.deadline = m.deadline.Add(-m.gap)
+		m.pausedTime -= m.gap
+		if !m.running {
+			m.remainingTime -= m.gap
+		}
+	}
+
+	m.gap = 0
 }
 
 func (m *PomodoroModel) reset() {
+	m.internalInterruptions = 0
+	m.externalInterruptions = 0
 	m.pausedTime    = time.Duration(0)
-	m.remainingTime = m.phasesDurations[m.phaseType]
-	m.running       = Config.Autostart
+	m.remainingTime = m.getPhase().duration
+	m.running       = false
+	m.setRunning(Config.Autostart)
 	m.progressBar.FullColor = m.getPhaseColor()
 }
 
-// It updates the whole state of the PomodoroModel
-func (m *PomodoroModel) next() {
-	PlayAlarm() // HACK: i know this function shouldn't hanle alarms but u know
+// Restarts the current phase with the options of the given profile
+func (m *PomodoroModel) switchProfile(name string) error {
+	if err := UseProfile(name); err != nil {
+		return err
+	}
+
+	phases := buildPhases()
+	m.n      = m.matchingPhase(phases)
+	m.phases = phases
+	m.reset()
+	m.emit(ProfileSwitchedEvent)
 
-	// NOTE: be careful n is updated first
-	m.n += 1
+	return nil
+}
+
+// The n of the phase of another sequence standing for the current one: the phase of its kind at
+// the same place among the phases of that kind, the last one of the kind when there are fewer of
+// them or the first phase when there is none. The loops already done are kept
+func (m *PomodoroModel) matchingPhase(phases []phase) uint64 {
+	position := int((m.n - 1) % uint64(len(m.phases)))
+	kind := m.phases[position].kind
+
+	occurrence := 0
+	for _, p := range m.phases[:position] {
+		if p.kind == kind {
+			occurrence++
+		}
+	}
 
-	var newPhaseType phaseType
+	match, seen := 0, 0
+	for i, p := range phases {
+		if p.kind != kind {
+			continue
+		}
 
-	switch m.phaseType {
-	case ShortBreak, LongBreak:
-		newPhaseType = Focus
-	// TODO: Checking next phase should be more configurable and not hard coded
-	case Focus:
-		if m.n == 7 || (m.n - 1) % 7 == 0 { // because for every 4 focus phases there are three short breaks in between
-			newPhaseType = LongBreak
-		} else {
-			newPhaseType = ShortBreak
+		match = i
+		if seen == occurrence {
+			break
 		}
+		seen++
 	}
 
+	cycles := (m.n - 1) / uint64(len(m.phases))
+	return cycles * uint64(len(phases)) + uint64(match) + 1
+}
+
+// It updates the whole state of the PomodoroModel
+func (m *PomodoroModel) next() {
+	alarm := m.alarm(m.getPhase().kind) // HACK: i know this function shouldn't hanle alarms but u know
+
+	m.advance(1, Config.Autostart)
+	m.notifyPhase(alarm)
+}
+
+// Moves k phases forward, the phase starts from its full duration
+func (m *PomodoroModel) advance(k uint64, running bool) {
+	m.n += k
+
+	m.internalInterruptions = 0
+	m.externalInterruptions = 0
 	m.pausedTime    = time.Duration(0)
-	m.remainingTime = m.phasesDurations[newPhaseType]
-	m.phaseType     = newPhaseType
-	m.running       = Config.Autostart
+	m.remainingTime = m.getPhase().duration
+	m.running       = false
+	m.setRunning(running)
 	m.progressBar.FullColor = m.getPhaseColor()
+	m.emit(PhaseStartedEvent)
+}
+
+// Skips to the next phase with the given name or kind and starts it
+func (m *PomodoroModel) jump(name string) error {
+	if !Config.Skipping {
+		return ErrSkippingNotAllowed
+	}
+
+	for k := uint64(1); k <= uint64(len(m.phases)); k++ {
+		p := m.phases[(m.n - 1 + k) % uint64(len(m.phases))]
+		if p.name != name && p.kind.String() != name {
+			continue
+		}
+
+		m.emit(SkippedEvent)
+		m.advance(k, true)
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q", ErrUnknownPhase, name)
 }
 
 func (m *PomodoroModel) resizeProgressBar(width int) {
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCycle(t *testing.T) {
+	m, clock := newTestModel(t, func(c *ConfigT) { c.Autostart = true })
+
+	// Two cycles of the default profile, the sequence loops after the long break
+	want := []phaseType{
+		Focus, ShortBreak, Focus, ShortBreak, Focus, ShortBreak, Focus, LongBreak,
+		Focus, ShortBreak,
+	}
+
+	for i, kind := range want {
+		if got := m.getPhase().kind; got != kind {
+			t.Fatalf("phase %d is %s, want %s", i + 1, got, kind)
+		}
+		if m.n != uint64(i + 1) {
+			t.Fatalf("n = %d, want %d", m.n, i + 1)
+		}
+
+		run(m, clock, m.getPhase().duration)
+	}
+
+	completed := 0
+	for _, e := range m.pending {
+		if e.event == CompletedEvent {
+			completed++
+		}
+	}
+	if completed != len(want) {
+		t.Errorf("%d completed events, want %d", completed, len(want))
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name       string
+		autostart  bool
+	}{
+		{"paused", false},
+		{"auto start", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, clock := newTestModel(t, func(c *ConfigT) { c.Autostart = test.autostart })
+
+			alarms := 0
+			m.alarm = func(kind phaseType) bool {
+				if kind != Focus {
+					t.Errorf("the alarm is for %s, want the focus phase that ended", kind)
+				}
+				alarms++
+				return true
+			}
+
+			m.setRunning(true)
+			clock.advance(time.Minute)
+			m.setRunning(false) // some paused time and progress to be cleared
+			m.pausedTime = time.Minute
+			m.pending = nil
+
+			m.next()
+
+			if alarms != 1 {
+				t.Errorf("the alarm played %d times, want once", alarms)
+			}
+			if m.n != 2 || m.getPhase().kind != ShortBreak {
+				t.Errorf("n = %d (%s), want 2 (short_break)", m.n, m.getPhase().kind)
+			}
+			if m.remainingTime != Config.Durations.ShortBreak || m.pausedTime != 0 {
+				t.Errorf("remaining %s paused %s, want %s and 0", m.remainingTime, m.pausedTime, Config.Durations.ShortBreak)
+			}
+			if m.running != test.autostart {
+				t.Errorf("running = %v, want %v", m.running, test.autostart)
+			}
+			if len(m.pending) != 1 || m.pending[0].event != PhaseStartedEvent {
+				t.Errorf("events = %v, want a phase_started event", m.pending)
+			}
+		})
+	}
+}
+
+func TestTick(t *testing.T) {
+	tests := []struct {
+		name       string
+		running    bool
+		elapsed    time.Duration
+		remaining  time.Duration
+		paused     time.Duration
+		gap        time.Duration
+	}{
+		{"running", true, time.Second * 5, time.Minute*25 - time.Second*5, 0, 0},
+		{"paused", false, time.Second * 5, time.Minute * 25, time.Second * 5, 0},
+		// The tick before the suspend is counted as elapsed
+		{"suspended while running", true, time.Hour, time.Minute*25 - Config.TickDuration, time.Hour - Config.TickDuration, time.Hour - Config.TickDuration},
+		{"suspended while paused", false, time.Hour, time.Minute * 25, time.Hour, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, clock := newTestModel(t, nil)
+			m.setRunning(test.running)
+
+			clock.advance(test.elapsed)
+			gap := m.tick(clock.Now())
+
+			if gap != test.gap {
+				t.Errorf("gap = %s, want %s", gap, test.gap)
+			}
+			if m.remainingTime != test.remaining {
+				t.Errorf("remaining = %s, want %s", m.remainingTime, test.remaining)
+			}
+			if m.pausedTime != test.paused {
+				t.Errorf("paused = %s, want %s", m.pausedTime, test.paused)
+			}
+		})
+	}
+}
+
+func TestPauseLimitReached(t *testing.T) {
+	tests := []struct {
+		name     string
+		running  bool
+		paused   time.Duration
+		reached  bool
+	}{
+		{"under the limit", false, time.Minute * 4, false},
+		{"at the limit", false, time.Minute * 5, true},
+		{"running", true, time.Minute * 10, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, clock := newTestModel(t, func(c *ConfigT) { c.MaxPauseDuration = time.Minute * 5 })
+			m.setRunning(test.running)
+
+			run(m, clock, test.paused)
+			if !test.running && m.pausedTime != test.paused {
+				t.Fatalf("paused = %s, want %s", m.pausedTime, test.paused)
+			}
+
+			if got := m.pauseLimitReached(); got != test.reached {
+				t.Errorf("pauseLimitReached() = %v, want %v", got, test.reached)
+			}
+		})
+	}
+}
+
+// A clock reading the real time with its monotonic reading, like time.Now()
+type monotonicClock struct {
+	start   time.Time
+	offset  time.Duration
+}
+
+func (c *monotonicClock) Now() time.Time {
+	return c.start.Add(c.offset)
+}
+
+func TestWallClock(t *testing.T) {
+	t.Run("sleep", func(t *testing.T) {
+		m, clock := newTestModel(t, nil)
+		m.setRunning(true)
+		run(m, clock, time.Minute)
+
+		// The wall clock jumps forward while the laptop sleeps
+		clock.advance(time.Minute * 30)
+		gap := m.tick(clock.Now())
+
+		if gap < time.Minute * 29 {
+			t.Errorf("gap = %s, want the 30m of the sleep", gap)
+		}
+		if want := time.Minute*24 - Config.TickDuration; m.remainingTime != want {
+			t.Errorf("remaining = %s, want %s", m.remainingTime, want)
+		}
+
+		// Counted as elapsed the phase ended during the sleep
+		m.countGap(true)
+		if left := m.deadline.Sub(clock.Now()); left != -time.Minute * 6 {
+			t.Errorf("the deadline is in %s after counting the sleep as elapsed, want -6m", left)
+		}
+	})
+
+	t.Run("no monotonic reading", func(t *testing.T) {
+		m, _ := newTestModel(t, nil)
+		clock := &monotonicClock{start: time.Now()}
+		m.clock = clock
+
+		m.setRunning(true)
+		clock.offset = time.Second
+		m.tick(clock.Now())
+
+		for name, v := range map[string]time.Time{"deadline": m.deadline, "lastTick": m.lastTick} {
+			if v != v.Round(0) {
+				t.Errorf("%s = %s, want it without the monotonic reading", name, v)
+			}
+		}
+	})
+
+	if now := SystemClock.Now(); now != now.Round(0) {
+		t.Errorf("SystemClock.Now() = %s, want it without the monotonic reading", now)
+	}
+}
+
+// Switching to a sequence of another length restarts a phase of the same kind at the same place
+func TestSwitchProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  ProfileConfigT
+		n        uint64 // in the default profile's cycle of 4 focus sessions
+		want     uint64
+		kind     phaseType
+	}{
+		{"third focus to 2 sessions", ProfileConfigT{Cycle: CycleConfigT{2}}, 5, 3, Focus},
+		{"second short break to 2 sessions", ProfileConfigT{Cycle: CycleConfigT{2}}, 4, 2, ShortBreak},
+		{"long break to 2 sessions", ProfileConfigT{Cycle: CycleConfigT{2}}, 8, 4, LongBreak},
+		{"second loop", ProfileConfigT{Cycle: CycleConfigT{2}}, 11, 7, Focus},
+		{"first focus to 6 sessions", ProfileConfigT{Cycle: CycleConfigT{6}}, 1, 1, Focus},
+		{"long break to 6 sessions", ProfileConfigT{Cycle: CycleConfigT{6}}, 8, 12, LongBreak},
+		{"long break to a sequence without one", ProfileConfigT{Sequence: []PhaseConfigT{
+			{Name: "focus", Kind: "focus", Duration: time.Minute * 50},
+			{Name: "short_break", Kind: "short_break", Duration: time.Minute * 10},
+		}}, 8, 1, Focus},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, _ := newTestModel(t, nil)
+
+			profile := Config.ProfileConfigT
+			if test.profile.Cycle.SessionsBeforeLongBreak != 0 {
+				profile.Cycle = test.profile.Cycle
+			}
+			profile.Sequence = test.profile.Sequence
+			Config.profiles = map[string]ProfileConfigT{DefaultProfile: Config.ProfileConfigT, "other": profile}
+
+			m.n = test.n
+			m.reset()
+
+			if err := m.switchProfile("other"); err != nil {
+				t.Fatal(err)
+			}
+
+			if m.n != test.want || m.getPhase().kind != test.kind {
+				t.Errorf("n = %d (%s), want %d (%s)", m.n, m.getPhase().kind, test.want, test.kind)
+			}
+			if m.remainingTime != m.getPhase().duration {
+				t.Errorf("remaining %s, want the full %s of the phase", m.remainingTime, m.getPhase().duration)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -10,6 +12,14 @@ type popupType int
 type PopupMsg struct {
 	Type    popupType
 	Content string
+	Actions []PopupAction // Answers the user can give to the popup, it's closed after answering
+}
+
+// Sends Msg when Key is pressed while the popup is shown
+type PopupAction struct {
+	Key     string
+	Label   string
+	Msg     tea.Msg
 }
 
 type ResetPopupsMsg struct {}
@@ -21,7 +31,7 @@ type PopupModel struct {
 const (
 	ErrorPopup popupType = iota
 	WarningPopup
-	AlarmPopup // XXX: rename this + find a use for it
+	AlarmPopup // Good news, e.g. reaching a goal
 )
 
 func (m *PopupModel) Init() tea.Cmd {
@@ -39,6 +49,9 @@ func (m *PopupModel) Update(msg tea.Msg) tea.Cmd {
 
 		case "ctrl+r":
 			m.popups = []PopupMsg{} // resetting
+
+		default:
+			cmd = m.answer(msg.String())
 		}
 
 	case PopupMsg:
@@ -48,6 +61,29 @@ func (m *PopupModel) Update(msg tea.Msg) tea.Cmd {
 	return cmd
 }
 
+// Answers the first popup having an action bound to key and removes it
+func (m *PopupModel) answer(key string) tea.Cmd {
+	for i, popup := range m.popups {
+		for _, action := range popup.Actions {
+			if action.Key != key {
+				continue
+			}
+
+			m.popups = append(m.popups[:i], m.popups[i+1:]...)
+			answer  := action.Msg
+			cmd     := func() tea.Msg { return answer }
+
+			if len(m.popups) == 0 {
+				cmd = tea.Sequence(cmd, func() tea.Msg { return InitPomodoroMsg{} })
+			}
+
+			return cmd
+		}
+	}
+
+	return nil
+}
+
 func (m *PopupModel) Render() string {
 	var popupsStr string
 
@@ -56,15 +92,25 @@ func (m *PopupModel) Render() string {
 		if i == len(m.popups) - 1 {
 			newline = ""
 		}
+		content := popup.Content
+		for _, action := range popup.Actions {
+			content += fmt.Sprintf("\n[%s] %s", action.Key, action.Label)
+		}
+
 		switch popup.Type {
-		case ErrorPopup:   popupsStr += GetErrorStyle().Render(popup.Content)   + newline
-		case WarningPopup: popupsStr += GetWarningStyle().Render(popup.Content) + newline
-		case AlarmPopup:   popupsStr += GetAlarmStyle().Render(popup.Content)   + newline
+		case ErrorPopup:   popupsStr += GetErrorStyle().Render(content)   + newline
+		case WarningPopup: popupsStr += GetWarningStyle().Render(content) + newline
+		case AlarmPopup:   popupsStr += GetAlarmStyle().Render(content)   + newline
 		}
 	}
 
 
-	content := lipgloss.JoinVertical(lipgloss.Center, "Ooops.", "")
+	title := "Ooops."
+	if len(m.popups) != 0 && m.popups[0].Type == AlarmPopup {
+		title = "Well done!"
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Center, title, "")
 	content = lipgloss.JoinVertical(
 		lipgloss.Center,
 		content,
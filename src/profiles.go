@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Opens the profile picker
+type OpenProfilesMsg struct{}
+
+// Sent by the profile picker when a profile is picked
+type SwitchProfileMsg struct {
+	Name string
+}
+
+type ProfilesModel struct {
+	names    []string
+	cursor   int
+}
+
+func (m *ProfilesModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *ProfilesModel) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc":
+			cmd = func() tea.Msg { return InitPomodoroMsg{} }
+
+		case "up", "k":
+			m.cursor = max(m.cursor - 1, 0)
+
+		case "down", "j":
+			m.cursor = min(m.cursor + 1, len(m.names) - 1)
+
+		case "enter":
+			name := m.names[m.cursor]
+			cmd = func() tea.Msg { return SwitchProfileMsg{Name: name} }
+		}
+
+	case OpenProfilesMsg:
+		m.names  = ProfileNames()
+		m.cursor = 0
+		for i, name := range m.names {
+			if name == Config.Profile {
+				m.cursor = i
+			}
+		}
+	}
+
+	return cmd
+}
+
+func (m *ProfilesModel) Render() string {
+	var list strings.Builder
+
+	for i, name := range m.names {
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "> "
+		}
+
+		if name == Config.Profile {
+			name += " (active)"
+		}
+
+		list.WriteString(cursor + name)
+		if i != len(m.names) - 1 {
+			list.WriteString("\n")
+		}
+	}
+
+	color := Config.ProgressBar.FocusColor
+
+	s := GetBorderStyle(color).Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render("Profiles"),
+			"",
+			list.String(),
+		),
+	)
+
+	return s
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"time"
+)
+
+// A snapshot of the timer, it's what the daemon sends to its clients
+type PomodoroState struct {
+	Phase       string     `json:"phase"`
+	Kind        string     `json:"kind"`
+	Duration    float64    `json:"duration"`  // seconds
+	Remaining   float64    `json:"remaining"` // seconds
+	Paused      float64    `json:"paused"`    // seconds
+	Running     bool       `json:"running"`
+	N           uint64     `json:"n"`
+	Session     int        `json:"session"`
+	Profile     string     `json:"profile"`
+	Task        string     `json:"task"` // ID of the task, empty for no task
+	TaskTitle   string     `json:"task_title"`
+	Internal    int        `json:"internal_interruptions"` // of the current phase
+	External    int        `json:"external_interruptions"`
+	Muted       bool       `json:"muted"` // the ambient sound
+	Deadline    time.Time  `json:"deadline"` // NOTE: only meaningful while running
+	Time        time.Time  `json:"time"`
+}
+
+func (m *PomodoroModel) state() PomodoroState {
+	phase := m.getPhase()
+
+	return PomodoroState{
+		Phase:      phase.name,
+		Kind:       phase.kind.String(),
+		Duration:   phase.duration.Seconds(),
+		Remaining:  m.remainingTime.Seconds(),
+		Paused:     m.pausedTime.Seconds(),
+		Running:    m.running,
+		N:          m.n,
+		Session:    m.getSession(),
+		Profile:    Config.Profile,
+		Task:       m.task.ID,
+		TaskTitle:  m.task.Title,
+		Internal:   m.internalInterruptions,
+		External:   m.externalInterruptions,
+		Muted:      m.muted,
+		Deadline:   m.deadline,
+		Time:       m.clock.Now(),
+	}
+}
+
+// The state right after an event, for the events written together that aren't the last one. The
+// task's title and the interruptions are the current ones
+func (m *PomodoroModel) eventState(e pomodoroEvent) PomodoroState {
+	s := m.state()
+
+	s.Phase     = e.phase
+	s.Kind      = e.kind.String()
+	s.Duration  = e.duration.Seconds()
+	s.Remaining = e.remainingTime.Seconds()
+	s.Paused    = e.pausedTime.Seconds()
+	s.Running   = e.running
+	s.N         = e.n
+	s.Session   = m.sessionOf(max(e.n, 1))
+	s.Profile   = e.profile
+	s.Time      = e.time_
+
+	if e.task != s.Task {
+		s.Task, s.TaskTitle = e.task, ""
+	}
+	if e.running {
+		s.Deadline = e.time_.Add(e.remainingTime)
+	}
+
+	return s
+}
+
+// Mirrors a state coming from the daemon, the phases are rebuilt if its profile is another one
+func (m *PomodoroModel) applyState(s PomodoroState) {
+	if s.Profile != Config.Profile && UseProfile(s.Profile) == nil {
+		m.phases = buildPhases()
+	}
+
+	if s.Task != m.task.ID {
+		m.task = taskItem{ID: s.Task, Title: s.TaskTitle}
+	}
+
+	m.n             = max(s.N, 1)
+	m.internalInterruptions = s.Internal
+	m.externalInterruptions = s.External
+	m.running       = s.Running
+	m.muted         = s.Muted
+	m.remainingTime = time.Duration(s.Remaining * float64(time.Second))
+	m.pausedTime    = time.Duration(s.Paused * float64(time.Second))
+	m.deadline      = s.Deadline
+	m.lastTick      = m.clock.Now()
+	m.progressBar.FullColor = m.getPhaseColor()
+}
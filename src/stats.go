@@ -0,0 +1,356 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// The totals of a period (a day, a week or a month), a task or a tag computed from the log
+type statsPeriod struct {
+	period     string
+	estimate   int // the estimated pomodoros of a task, only when grouping by task
+	pomodoros  int // completed focus phases
+	skipped    int
+	ended      int // completed or skipped phases
+	internal   int // interruptions
+	external   int
+	focus      time.Duration
+	paused     time.Duration
+}
+
+// statsPeriod as `plumadoro stats --format json` prints it
+type statsPeriodJSON struct {
+	Period     string   `json:"period"`
+	Pomodoros  int      `json:"pomodoros"`
+	Estimate   int      `json:"estimate,omitempty"`
+	Focus      float64  `json:"focus"`     // seconds
+	Skipped    int      `json:"skipped"`
+	Paused     float64  `json:"paused"`    // seconds
+	AvgPause   float64  `json:"avg_pause"` // seconds
+	Internal   int      `json:"internal_interruptions"`
+	External   int      `json:"external_interruptions"`
+	Rate       float64  `json:"interruptions_per_pomodoro"`
+}
+
+// How the events are grouped, it returns the periods (or the task or its tags) an event falls in
+var statsGroupings = map[string]func(e pomodoroEvent) []string{
+	"day": func(e pomodoroEvent) []string {
+		return []string{e.time_.Format(time.DateOnly)}
+	},
+	"week": func(e pomodoroEvent) []string {
+		year, week := e.time_.ISOWeek()
+		return []string{fmt.Sprintf("%d-W%02d", year, week)}
+	},
+	"month": func(e pomodoroEvent) []string {
+		return []string{e.time_.Format("2006-01")}
+	},
+	"task": func(e pomodoroEvent) []string {
+		return []string{e.task}
+	},
+	// The time of a task is counted in every one of its tags
+	"tag": func(e pomodoroEvent) []string {
+		if len(e.tags) == 0 {
+			return []string{""}
+		}
+		return e.tags
+	},
+}
+
+var ErrInvalidStatsFlag = errors.New("Invalid stats flag")
+
+func (s *statsPeriod) avgPause() time.Duration {
+	if s.ended == 0 {
+		return 0
+	}
+
+	return s.paused / time.Duration(s.ended)
+}
+
+// Whether e is a later state of the same phase as prev, fresh phases start from their full
+// duration so the time isn't counted across them
+func samePhase(prev pomodoroEvent, e pomodoroEvent) bool {
+	switch (e.event) {
+	case PhaseStartedEvent, ResetEvent, ProfileSwitchedEvent:
+		return false
+	}
+
+	return prev.n == e.n && prev.phase == e.phase && sameDay(prev.time_, e.time_)
+}
+
+// Sums the events in [since, until) by the periods group returns, in the order they happened.
+// The focus & paused time is how much the remaining & paused time changed between the events,
+// it's counted in the period of the event before (e.g. the task that was active until a switch)
+func summarize(events []pomodoroEvent, group func(e pomodoroEvent) []string, since time.Time, until time.Time) []*statsPeriod {
+	var periods []*statsPeriod
+	byName := map[string]*statsPeriod{}
+
+	groups := func(e pomodoroEvent) []*statsPeriod {
+		var found []*statsPeriod
+		for _, name := range group(e) {
+			s, ok := byName[name]
+			if !ok {
+				s = &statsPeriod{period: name}
+				byName[name] = s
+				periods = append(periods, s)
+			}
+			found = append(found, s)
+		}
+		return found
+	}
+
+	for i, e := range events {
+		if e.time_.Before(since) || (!until.IsZero() && !e.time_.Before(until)) {
+			continue
+		}
+
+		for _, s := range groups(e) {
+			switch (e.event) {
+			case CompletedEvent:
+				s.ended++
+				if e.kind == Focus {
+					s.pomodoros++
+				}
+			case SkippedEvent:
+				s.ended++
+				s.skipped++
+			case InternalInterruptionEvent:
+				s.internal++
+			case ExternalInterruptionEvent:
+				s.external++
+			}
+		}
+
+		if i == 0 || !samePhase(events[i - 1], e) {
+			continue
+		}
+		prev := events[i - 1]
+
+		for _, s := range groups(prev) {
+			if e.kind == Focus {
+				s.focus += max(prev.remainingTime - e.remainingTime, 0)
+			}
+			s.paused += max(e.pausedTime - prev.pausedTime, 0)
+		}
+	}
+
+	return periods
+}
+
+func parseStatsDate(value string, name string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return t, fmt.Errorf("%w: --%s must be a date like 2024-01-31", ErrInvalidStatsFlag, name)
+	}
+
+	return t, nil
+}
+
+func runStats(args []string) error {
+	var by, format, sinceFlag, untilFlag string
+
+	_, _, err := setup("stats", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&by,        "by",     "day",   `group by "day", "week", "month", "task" or "tag"`)
+		fs.StringVar(&format,    "format", "table", `"table", "csv" or "json"`)
+		fs.StringVar(&sinceFlag, "since",  "",      "only the days from this date (e.g. 2024-01-01)")
+		fs.StringVar(&untilFlag, "until",  "",      "only the days until this date, included")
+	})
+	if err != nil {
+		return err
+	}
+
+	group, ok := statsGroupings[by]
+	if !ok {
+		return fmt.Errorf("%w: --by must be \"day\", \"week\", \"month\", \"task\" or \"tag\"", ErrInvalidStatsFlag)
+	}
+
+	since, err := parseStatsDate(sinceFlag, "since")
+	if err != nil {
+		return err
+	}
+
+	until, err := parseStatsDate(untilFlag, "until")
+	if err != nil {
+		return err
+	}
+	if !until.IsZero() {
+		until = until.AddDate(0, 0, 1)
+	}
+
+	events, err := readAllEvents()
+	if err != nil {
+		return err
+	}
+
+	periods := summarize(events, group, since, until)
+	switch (by) {
+	case "task":
+		if err := nameTasks(periods); err != nil {
+			return err
+		}
+	case "tag":
+		for _, s := range periods {
+			if s.period == "" {
+				s.period = "(no tag)"
+			}
+		}
+	}
+
+	switch (format) {
+	case "table":
+		return printStatsTable(periods, by)
+	case "csv":
+		return printStatsCSV(periods)
+	case "json":
+		return printStatsJSON(periods)
+	}
+
+	return fmt.Errorf("%w: --format must be \"table\", \"csv\" or \"json\"", ErrInvalidStatsFlag)
+}
+
+// Replaces the IDs of the tasks by their titles and sets their estimates
+func nameTasks(periods []*statsPeriod) error {
+	items, err := newTaskSource().list()
+	if err != nil {
+		return err
+	}
+
+	byID := map[string]taskItem{}
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	for _, s := range periods {
+		if s.period == "" {
+			s.period = "(no task)"
+			continue
+		}
+
+		item, ok := byID[s.period]
+		if !ok {
+			s.period += " (not in the task source)"
+			continue
+		}
+
+		s.period   = item.Title
+		s.estimate = int(item.Estimate)
+	}
+
+	return nil
+}
+
+// The interruptions per completed pomodoro
+func (s *statsPeriod) interruptionRate() float64 {
+	if s.pomodoros == 0 {
+		return 0
+	}
+
+	return float64(s.internal + s.external) / float64(s.pomodoros)
+}
+
+// The pomodoros column, with the estimate of a task if it has one
+func (s *statsPeriod) pomodorosColumn() string {
+	if s.estimate == 0 {
+		return strconv.Itoa(s.pomodoros)
+	}
+
+	return fmt.Sprintf("%d/%d", s.pomodoros, s.estimate)
+}
+
+// Formats a duration as 1h05m
+func hhmm(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes()) % 60)
+}
+
+func printStatsTable(periods []*statsPeriod, by string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "%s\tPOMODOROS\tFOCUS\tSKIPPED\tPAUSED\tAVG PAUSE\tINTERNAL\tEXTERNAL\tPER POMODORO\t\n",
+		strings.ToUpper(by))
+
+	var total statsPeriod
+	for _, s := range periods {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%d\t%d\t%.2f\t\n",
+			s.period, s.pomodorosColumn(), hhmm(s.focus), s.skipped, hhmm(s.paused), hhmm(s.avgPause()),
+			s.internal, s.external, s.interruptionRate())
+
+		total.pomodoros += s.pomodoros
+		total.skipped   += s.skipped
+		total.ended     += s.ended
+		total.focus     += s.focus
+		total.paused    += s.paused
+		total.internal  += s.internal
+		total.external  += s.external
+	}
+
+	if len(periods) > 1 {
+		fmt.Fprintf(w, "TOTAL\t%d\t%s\t%d\t%s\t%s\t%d\t%d\t%.2f\t\n",
+			total.pomodoros, hhmm(total.focus), total.skipped, hhmm(total.paused), hhmm(total.avgPause()),
+			total.internal, total.external, total.interruptionRate())
+	}
+
+	return w.Flush()
+}
+
+func printStatsCSV(periods []*statsPeriod) error {
+	seconds := func(d time.Duration) string {
+		return strconv.Itoa(int(d.Seconds()))
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"period", "pomodoros", "focus", "skipped", "paused", "avg_pause", "estimate",
+		"internal_interruptions", "external_interruptions", "interruptions_per_pomodoro"})
+
+	for _, s := range periods {
+		w.Write([]string{
+			s.period,
+			strconv.Itoa(s.pomodoros),
+			seconds(s.focus),
+			strconv.Itoa(s.skipped),
+			seconds(s.paused),
+			seconds(s.avgPause()),
+			strconv.Itoa(s.estimate),
+			strconv.Itoa(s.internal),
+			strconv.Itoa(s.external),
+			strconv.FormatFloat(s.interruptionRate(), 'f', 2, 64),
+		})
+	}
+	w.Flush()
+
+	return w.Error()
+}
+
+func printStatsJSON(periods []*statsPeriod) error {
+	out := make([]statsPeriodJSON, 0, len(periods))
+
+	for _, s := range periods {
+		out = append(out, statsPeriodJSON{
+			Period:    s.period,
+			Pomodoros: s.pomodoros,
+			Estimate:  s.estimate,
+			Focus:     s.focus.Seconds(),
+			Skipped:   s.skipped,
+			Paused:    s.paused.Seconds(),
+			AvgPause:  s.avgPause().Seconds(),
+			Internal:  s.internal,
+			External:  s.external,
+			Rate:      s.interruptionRate(),
+		})
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(out)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// The event at the given local time, like "2026-03-02 09:10"
+func at(e pomodoroEvent, when string) pomodoroEvent {
+	e.time_, _ = time.ParseInLocation(time.DateTime, when + ":00", time.Local)
+	return e
+}
+
+func withTask(e pomodoroEvent, task string) pomodoroEvent {
+	e.task = task
+	return e
+}
+
+func statsEvents() []pomodoroEvent {
+	return []pomodoroEvent{
+		// Monday
+		withTask(at(testEvent(0, PhaseStartedEvent, 1, time.Minute * 25, 0, true), "2026-03-02 09:00"), "1"),
+		withTask(at(testEvent(0, PausedEvent, 1, time.Minute * 15, 0, false), "2026-03-02 09:10"), "1"),
+		withTask(at(testEvent(0, ResumedEvent, 1, time.Minute * 15, time.Minute * 5, true), "2026-03-02 09:15"), "1"),
+		withTask(at(testEvent(0, InternalInterruptionEvent, 1, time.Minute * 14, time.Minute * 5, true), "2026-03-02 09:16"), "1"),
+		withTask(at(testEvent(0, TaskSwitchedEvent, 1, time.Minute * 10, time.Minute * 5, true), "2026-03-02 09:20"), "2"),
+		withTask(at(testEvent(0, CompletedEvent, 1, 0, time.Minute * 5, true), "2026-03-02 09:30"), "2"),
+		withTask(at(testEvent(0, PhaseStartedEvent, 2, time.Minute * 5, 0, true), "2026-03-02 09:30"), "2"),
+		withTask(at(testEvent(0, CompletedEvent, 2, 0, 0, true), "2026-03-02 09:35"), "2"),
+		// The time before a reset or a profile switch isn't counted, the phase starts over
+		withTask(at(testEvent(0, PhaseStartedEvent, 3, time.Minute * 25, 0, true), "2026-03-02 09:35"), "2"),
+		withTask(at(testEvent(0, ResetEvent, 3, time.Minute * 25, 0, true), "2026-03-02 09:40"), "2"),
+		withTask(at(testEvent(0, ProfileSwitchedEvent, 3, time.Minute * 25, 0, true), "2026-03-02 09:41"), "2"),
+		withTask(at(testEvent(0, SkippedEvent, 3, time.Minute * 16, 0, true), "2026-03-02 09:50"), "2"),
+		// Across midnight the time is only counted from the first event of the day
+		withTask(at(testEvent(0, PhaseStartedEvent, 5, time.Minute * 25, 0, true), "2026-03-02 23:50"), "1"),
+		withTask(at(testEvent(0, PausedEvent, 5, time.Minute * 15, 0, false), "2026-03-03 00:00"), "1"),
+		withTask(at(testEvent(0, ResumedEvent, 5, time.Minute * 15, time.Minute * 2, true), "2026-03-03 00:02"), "1"),
+		withTask(at(testEvent(0, CompletedEvent, 5, 0, time.Minute * 2, true), "2026-03-03 00:17"), "1"),
+		// The next week
+		at(testEvent(0, PhaseStartedEvent, 1, time.Minute * 25, 0, true), "2026-03-09 09:00"),
+		at(testEvent(0, ExternalInterruptionEvent, 1, time.Minute * 20, 0, true), "2026-03-09 09:05"),
+		at(testEvent(0, CompletedEvent, 1, 0, 0, true), "2026-03-09 09:25"),
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name   string
+		by     string
+		since  string
+		until  string
+		want   []statsPeriod
+	}{
+		{"by day", "day", "", "", []statsPeriod{
+			{period: "2026-03-02", pomodoros: 1, skipped: 1, ended: 3, internal: 1,
+				focus: time.Minute * 34, paused: time.Minute * 5},
+			{period: "2026-03-03", pomodoros: 1, ended: 1, focus: time.Minute * 15, paused: time.Minute * 2},
+			{period: "2026-03-09", pomodoros: 1, ended: 1, external: 1, focus: time.Minute * 25},
+		}},
+		{"by week", "week", "", "", []statsPeriod{
+			{period: "2026-W10", pomodoros: 2, skipped: 1, ended: 4, internal: 1,
+				focus: time.Minute * 49, paused: time.Minute * 7},
+			{period: "2026-W11", pomodoros: 1, ended: 1, external: 1, focus: time.Minute * 25},
+		}},
+		// The time until a switch is the task's that was active
+		{"by task", "task", "", "", []statsPeriod{
+			{period: "1", pomodoros: 1, ended: 1, internal: 1, focus: time.Minute * 30, paused: time.Minute * 7},
+			{period: "2", pomodoros: 1, skipped: 1, ended: 3, focus: time.Minute * 19},
+			{period: "", pomodoros: 1, ended: 1, external: 1, focus: time.Minute * 25},
+		}},
+		{"since", "day", "2026-03-03", "", []statsPeriod{
+			{period: "2026-03-03", pomodoros: 1, ended: 1, focus: time.Minute * 15, paused: time.Minute * 2},
+			{period: "2026-03-09", pomodoros: 1, ended: 1, external: 1, focus: time.Minute * 25},
+		}},
+		{"until", "day", "", "2026-03-03", []statsPeriod{
+			{period: "2026-03-02", pomodoros: 1, skipped: 1, ended: 3, internal: 1,
+				focus: time.Minute * 34, paused: time.Minute * 5},
+			{period: "2026-03-03", pomodoros: 1, ended: 1, focus: time.Minute * 15, paused: time.Minute * 2},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useConfig(t, defaultConfig)
+
+			since, _ := parseStatsDate(test.since, "since")
+			until, _ := parseStatsDate(test.until, "until")
+			if !until.IsZero() {
+				until = until.AddDate(0, 0, 1) // the day is included like runStats does
+			}
+
+			periods := summarize(statsEvents(), statsGroupings[test.by], since, until)
+
+			if len(periods) != len(test.want) {
+				t.Fatalf("%d periods, want %d", len(periods), len(test.want))
+			}
+			for i, got := range periods {
+				if *got != test.want[i] {
+					t.Errorf("period %d = %+v, want %+v", i, *got, test.want[i])
+				}
+			}
+		})
+	}
+}
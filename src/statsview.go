@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Opens the statistics view
+type OpenStatsMsg struct{}
+
+const (
+	heatmapWeeks  int = 20 // columns of the heatmap
+	barChartDays  int = 7
+	barChartWidth int = 30
+)
+
+// The cells of the heatmap from no focus to the most focused days
+var heatmapLevels = []string{"·", "░", "▒", "▓", "█"}
+
+type StatsModel struct {
+	days   map[string]*statsPeriod // by date, read from the log when the view is opened
+	err    error
+	clock  Clock
+}
+
+func (m *StatsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *StatsModel) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "q" || msg.String() == "esc" || key.Matches(msg, newKeyMap().Stats) {
+			cmd = func() tea.Msg { return InitPomodoroMsg{} }
+		}
+
+	case OpenStatsMsg:
+		m.load()
+	}
+
+	return cmd
+}
+
+func (m *StatsModel) load() {
+	m.days = map[string]*statsPeriod{}
+
+	events, err := readAllEvents()
+	m.err = err
+	if err != nil {
+		return
+	}
+
+	for _, s := range summarize(events, statsGroupings["day"], time.Time{}, time.Time{}) {
+		m.days[s.period] = s
+	}
+}
+
+func (m *StatsModel) day(t time.Time) statsPeriod {
+	if s, ok := m.days[t.Format(time.DateOnly)]; ok {
+		return *s
+	}
+
+	return statsPeriod{}
+}
+
+// The start of the day t is in
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// The monday of the week t is in
+func startOfWeek(t time.Time) time.Time {
+	weekday := (int(t.Weekday()) + 6) % 7 // monday is 0
+	return startOfDay(t).AddDate(0, 0, -weekday)
+}
+
+func (m *StatsModel) renderTotals(today time.Time) string {
+	var week statsPeriod
+	for d := startOfWeek(today); !d.After(today); d = d.AddDate(0, 0, 1) {
+		s := m.day(d)
+		week.pomodoros += s.pomodoros
+		week.focus     += s.focus
+	}
+
+	day := m.day(today)
+
+	s := fmt.Sprintf("Today: %d pomodoros, %s focus, %d internal & %d external interruptions\n" +
+		"This week: %d pomodoros, %s focus",
+		day.pomodoros, hhmm(day.focus), day.internal, day.external, week.pomodoros, hhmm(week.focus))
+
+	if dailyGoalEnabled() {
+		// Today doesn't break the streak until it's over
+		streak := 0
+		d := today
+		if !dailyGoalMet(day) {
+			d = d.AddDate(0, 0, -1)
+		}
+		for ; dailyGoalMet(m.day(d)); d = d.AddDate(0, 0, -1) {
+			streak++
+		}
+
+		s += "\nStreak: " + plural(streak, "day")
+	}
+
+	return s
+}
+
+// A row per weekday and a column per week like GitHub's contributions calendar, the levels are
+// quarters of the most focused day shown
+func (m *StatsModel) renderHeatmap(today time.Time) string {
+	first := startOfWeek(today).AddDate(0, 0, -7 * (heatmapWeeks - 1))
+
+	var most time.Duration
+	for d := first; !d.After(today); d = d.AddDate(0, 0, 1) {
+		most = max(most, m.day(d).focus)
+	}
+
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(Config.ProgressBar.FocusColor))
+	weekdays := []string{"Mon", "   ", "Wed", "   ", "Fri", "   ", "Sun"}
+
+	var rows []string
+	for weekday := range 7 {
+		var row strings.Builder
+		row.WriteString(weekdays[weekday] + " ")
+
+		for week := range heatmapWeeks {
+			d := first.AddDate(0, 0, week * 7 + weekday)
+			if d.After(today) {
+				row.WriteString("  ")
+				continue
+			}
+
+			level := 0
+			if focus := m.day(d).focus; focus > 0 {
+				level = 1 + int(3 * focus / most)
+			}
+			row.WriteString(style.Render(heatmapLevels[min(level, len(heatmapLevels) - 1)]) + " ")
+		}
+
+		rows = append(rows, row.String())
+	}
+
+	return strings.Join(rows, "\n")
+}
+
+// The focus time of the last days as bars
+func (m *StatsModel) renderBars(today time.Time) string {
+	var most time.Duration
+	for i := range barChartDays {
+		most = max(most, m.day(today.AddDate(0, 0, -i)).focus)
+	}
+
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(Config.ProgressBar.FocusColor))
+
+	var rows []string
+	for i := barChartDays - 1; i >= 0; i-- {
+		d := today.AddDate(0, 0, -i)
+		focus := m.day(d).focus
+
+		width := 0
+		if most > 0 {
+			width = int(int64(barChartWidth) * int64(focus) / int64(most))
+		}
+
+		rows = append(rows, fmt.Sprintf("%s %s %s",
+			d.Format("Mon 01-02"),
+			style.Render(strings.Repeat("█", width)) + strings.Repeat(" ", barChartWidth - width),
+			hhmm(focus)))
+	}
+
+	return strings.Join(rows, "\n")
+}
+
+func (m *StatsModel) Render() string {
+	color := Config.ProgressBar.FocusColor
+	title := lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render("Statistics")
+
+	var body string
+	if m.err != nil {
+		body = m.err.Error()
+	} else {
+		today := startOfDay(m.clock.Now())
+
+		body = lipgloss.JoinVertical(
+			lipgloss.Left,
+			m.renderTotals(today),
+			"",
+			m.renderHeatmap(today),
+			"",
+			m.renderBars(today),
+		)
+	}
+
+	return GetBorderStyle(color).Render(
+		lipgloss.JoinVertical(lipgloss.Left, title, "", body),
+	)
+}
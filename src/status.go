@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// The formats of `plumadoro status --format` other than Go templates
+const (
+	textStatusFormat   string = "{{.Phase}} {{mmss .Remaining}} #{{.Session}}{{if not .Running}} (paused){{end}}"
+	jsonStatusFormat   string = "json"
+	waybarStatusFormat string = "waybar"
+)
+
+// What waybar's custom modules expect when "return-type" is "json"
+type waybarStatus struct {
+	Text        string    `json:"text"`
+	Alt         string    `json:"alt"`
+	Tooltip     string    `json:"tooltip"`
+	Class       []string  `json:"class"`
+	Percentage  int       `json:"percentage"`
+}
+
+var statusFuncs = template.FuncMap{
+	"mmss": mmss,
+}
+
+func mmss(seconds float64) string {
+	return fmt.Sprintf("%02d:%02d", int(seconds) / 60, int(seconds) % 60)
+}
+
+func runStatus(args []string) error {
+	var format string
+
+	_, _, err := setup("status", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&format, "format", textStatusFormat,
+			`"json", "waybar" or a Go template of the state (e.g. "{{.Phase}} {{mmss .Remaining}}")`)
+	})
+	if err != nil {
+		return err
+	}
+
+	state, err := currentState()
+	if err != nil {
+		return err
+	}
+
+	switch (format) {
+	case jsonStatusFormat:
+		return json.NewEncoder(os.Stdout).Encode(state)
+
+	case waybarStatusFormat:
+		return json.NewEncoder(os.Stdout).Encode(toWaybarStatus(state))
+	}
+
+	tmpl, err := template.New("status").Funcs(statusFuncs).Parse(format)
+	if err != nil {
+		return err
+	}
+
+	if err := tmpl.Execute(os.Stdout, state); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	return nil
+}
+
+// The live state from the daemon, or the last state saved in the log when it isn't running
+func currentState() (PomodoroState, error) {
+	response, err := callDaemon(socketPath(), daemonRequest{Cmd: "status"})
+	if err == nil && response.Ok && response.State != nil {
+		return *response.State, nil
+	}
+
+	record, err := readLastEvent()
+	if err != nil {
+		return PomodoroState{}, err
+	}
+
+	return stateFromRecord(record, SystemClock.Now()), nil
+}
+
+// The state a record would have now, the time since it was saved is elapsed if it was running
+func stateFromRecord(record pomodoroEvent, now time.Time) PomodoroState {
+	if record.profile != "" && !Config.profileFromFlag {
+		UseProfile(record.profile)
+	}
+
+	record = record.at(now)
+
+	m := PomodoroModel{
+		n:              max(record.n, 1),
+		phases:         buildPhases(),
+		remainingTime:  record.remainingTime,
+		pausedTime:     record.pausedTime,
+		running:        record.running && record.event != AppQuitEvent,
+		clock:          SystemClock,
+	}
+
+	if m.running {
+		m.deadline = now.Add(m.remainingTime)
+	}
+
+	state := m.state()
+	state.Phase = record.phase // NOTE: in case the phases changed since the record was saved
+
+	return state
+}
+
+func toWaybarStatus(state PomodoroState) waybarStatus {
+	status := "running"
+	if !state.Running {
+		status = "paused"
+	}
+
+	percentage := 0
+	if state.Duration > 0 {
+		percentage = int(100 * (1 - state.Remaining / state.Duration))
+	}
+
+	return waybarStatus{
+		Text:       mmss(state.Remaining),
+		Alt:        state.Phase,
+		Tooltip:    fmt.Sprintf("%s #%d (%s)", strings.ReplaceAll(state.Phase, "_", " "), state.Session, status),
+		Class:      []string{state.Kind, status},
+		Percentage: percentage,
+	}
+}
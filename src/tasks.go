@@ -0,0 +1,442 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The tasks come from a source picked in the config (plumadoro's own tasks file, a todo.txt file
+// or Taskwarrior), the log has the ID & tags of the active task in every event so the pomodoros
+// and focus time can be counted by task or tag from it
+
+// A task as plumadoro keeps it in its own tasks file
+type Task struct {
+	ID        uint64     `toml:"id"`
+	Title     string     `toml:"title"`
+	Estimate  uint8      `toml:"estimate"` // in pomodoros, 0 when not estimated
+	Done      bool       `toml:"done"`
+	Created   time.Time  `toml:"created"`
+}
+
+type taskStore struct {
+	NextID  uint64  `toml:"next_id"`
+	Tasks   []Task  `toml:"tasks"`
+}
+
+// A task of any source
+type taskItem struct {
+	ID        string // unique among the sources e.g. "3", "todo:1a2b3c4d" or "tw:<uuid>"
+	Title     string
+	Estimate  uint8
+	Done      bool
+	Priority  string   // "A" is the highest, empty when it has none
+	Tags      []string // +project & @context
+}
+
+type taskSource interface {
+	list() ([]taskItem, error)
+	add(title string, now time.Time) error
+	setDone(id string, done bool, now time.Time) error
+	setEstimate(id string, estimate uint8) error
+}
+
+// Opens the task panel
+type OpenTasksMsg struct{}
+
+// Sent by the task panel when a task is picked, ID is empty to work without a task
+type SelectTaskMsg struct {
+	ID string
+}
+
+var (
+	ErrUnknownTask       = errors.New("Unknown task")
+	ErrTaskNotSupported  = errors.New("Not supported by the task source")
+)
+
+var tasksPath string = fmt.Sprintf("%s/plumadoro_tasks.toml", configDir)
+
+const maxTaskTitleLen int = 128
+
+func newTaskSource() taskSource {
+	switch (Config.Tasks.Source) {
+	case "todotxt":
+		return todoTxtTasks{path: expandHome(Config.Tasks.TodoTxt)}
+	case "taskwarrior":
+		return taskwarriorTasks{command: Config.Tasks.Taskwarrior}
+	}
+
+	return builtinTasks{}
+}
+
+// The task with the given ID in the configured source
+func findTask(id string) (taskItem, error) {
+	items, err := newTaskSource().list()
+	if err != nil {
+		return taskItem{}, err
+	}
+
+	for _, item := range items {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+
+	return taskItem{}, fmt.Errorf("%w: %q", ErrUnknownTask, id)
+}
+
+// The words of a title starting with + (projects) or @ (contexts) like in todo.txt
+func parseTags(title string) []string {
+	var tags []string
+
+	for _, word := range strings.Fields(title) {
+		if len(word) > 1 && (word[0] == '+' || word[0] == '@') {
+			tags = append(tags, word)
+		}
+	}
+
+	return tags
+}
+
+// The undone tasks first then by priority, the order of the source is kept otherwise
+func sortTasks(items []taskItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Done != items[j].Done {
+			return !items[i].Done
+		}
+
+		pi, pj := items[i].Priority, items[j].Priority
+		if pi == "" || pj == "" {
+			return pi != "" && pj == ""
+		}
+		return pi < pj
+	})
+}
+
+// The completed pomodoros of every task counted from the log
+func countTaskPomodoros() map[string]int {
+	counts := map[string]int{}
+
+	events, err := readAllEvents()
+	if err != nil {
+		return counts
+	}
+
+	for _, e := range events {
+		if e.event == CompletedEvent && e.kind == Focus && e.task != "" {
+			counts[e.task]++
+		}
+	}
+
+	return counts
+}
+
+
+// plumadoro's own tasks file
+
+type builtinTasks struct{}
+
+// A missing file is an empty store
+func loadTasks() (taskStore, error) {
+	store := taskStore{NextID: 1}
+
+	_, err := toml.DecodeFile(tasksPath, &store)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return store, fmt.Errorf("Failed reading the tasks: %w", err)
+	}
+
+	return store, nil
+}
+
+func (s *taskStore) save() error {
+	// Written to a temporary file first so a failure doesn't lose the tasks
+	tmp := tasksPath + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("Failed saving the tasks: %w", err)
+	}
+
+	err = toml.NewEncoder(file).Encode(s)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("Failed saving the tasks: %w", err)
+	}
+
+	return os.Rename(tmp, tasksPath)
+}
+
+func (s *taskStore) find(id string) *Task {
+	for i := range s.Tasks {
+		if strconv.FormatUint(s.Tasks[i].ID, 10) == id {
+			return &s.Tasks[i]
+		}
+	}
+
+	return nil
+}
+
+func (builtinTasks) list() ([]taskItem, error) {
+	store, err := loadTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]taskItem, 0, len(store.Tasks))
+	for _, task := range store.Tasks {
+		items = append(items, taskItem{
+			ID:       strconv.FormatUint(task.ID, 10),
+			Title:    task.Title,
+			Estimate: task.Estimate,
+			Done:     task.Done,
+			Tags:     parseTags(task.Title),
+		})
+	}
+
+	return items, nil
+}
+
+// Loads the store, changes the task with the given ID and saves it
+func (builtinTasks) update(id string, change func(task *Task)) error {
+	store, err := loadTasks()
+	if err != nil {
+		return err
+	}
+
+	task := store.find(id)
+	if task == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownTask, id)
+	}
+	change(task)
+
+	return store.save()
+}
+
+func (builtinTasks) add(title string, now time.Time) error {
+	store, err := loadTasks()
+	if err != nil {
+		return err
+	}
+
+	store.Tasks = append(store.Tasks, Task{ID: store.NextID, Title: title, Created: now})
+	store.NextID++
+
+	return store.save()
+}
+
+func (b builtinTasks) setDone(id string, done bool, now time.Time) error {
+	return b.update(id, func(task *Task) { task.Done = done })
+}
+
+func (b builtinTasks) setEstimate(id string, estimate uint8) error {
+	return b.update(id, func(task *Task) { task.Estimate = estimate })
+}
+
+
+type TasksModel struct {
+	source     taskSource
+	items      []taskItem
+	done       map[string]int // completed pomodoros by task
+	active     string
+	cursor     int
+	adding     bool
+	input      lineInput // the title of the task being added
+	err        error
+	clock      Clock
+}
+
+func (m *TasksModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *TasksModel) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.adding {
+			m.updateInput(msg)
+			break
+		}
+
+		if key.Matches(msg, newKeyMap().Tasks) {
+			cmd = func() tea.Msg { return InitPomodoroMsg{} }
+			break
+		}
+
+		switch msg.String() {
+		case "q", "esc":
+			cmd = func() tea.Msg { return InitPomodoroMsg{} }
+
+		case "up", "k":
+			m.cursor = max(m.cursor - 1, 0)
+
+		case "down", "j":
+			m.cursor = max(min(m.cursor + 1, len(m.items) - 1), 0)
+
+		case "a":
+			m.adding = true
+			m.input  = lineInput{maxLen: maxTaskTitleLen}
+
+		case "enter":
+			// Picking the active task again works without a task
+			id := ""
+			if item := m.selected(); item != nil && item.ID != m.active {
+				id = item.ID
+			}
+			cmd = func() tea.Msg { return SelectTaskMsg{ID: id} }
+
+		case "x":
+			if item := m.selected(); item != nil {
+				m.change(item.ID, m.source.setDone(item.ID, !item.Done, m.clock.Now()))
+			}
+
+		case "+", "=":
+			if item := m.selected(); item != nil && item.Estimate < 99 {
+				m.change(item.ID, m.source.setEstimate(item.ID, item.Estimate + 1))
+			}
+
+		case "-":
+			if item := m.selected(); item != nil && item.Estimate > 0 {
+				m.change(item.ID, m.source.setEstimate(item.ID, item.Estimate - 1))
+			}
+		}
+
+	case OpenTasksMsg:
+		m.source = newTaskSource()
+		m.done   = countTaskPomodoros()
+		m.adding = false
+		m.err    = nil
+		m.reload(m.active)
+	}
+
+	return cmd
+}
+
+// Reads the tasks again and puts the cursor on the task with the given ID
+func (m *TasksModel) reload(id string) {
+	var err error
+	m.items, err = m.source.list()
+	if m.err == nil {
+		m.err = err
+	}
+	sortTasks(m.items)
+
+	m.cursor = min(m.cursor, max(len(m.items) - 1, 0))
+	for i, item := range m.items {
+		if item.ID == id {
+			m.cursor = i
+		}
+	}
+}
+
+// Reloads the tasks after changing one of them
+func (m *TasksModel) change(id string, err error) {
+	m.err = err
+	m.reload(id)
+}
+
+func (m *TasksModel) updateInput(msg tea.KeyMsg) {
+	submitted, cancelled := m.input.update(msg)
+	if cancelled {
+		m.adding = false
+	}
+	if !submitted {
+		return
+	}
+
+	m.adding = false
+
+	title := m.input.value()
+	if title == "" {
+		return
+	}
+
+	m.err = m.source.add(title, m.clock.Now())
+	m.reload("")
+	for i, item := range m.items {
+		if item.Title == title {
+			m.cursor = i
+		}
+	}
+}
+
+func (m *TasksModel) selected() *taskItem {
+	if m.cursor < 0 || m.cursor >= len(m.items) {
+		return nil
+	}
+
+	return &m.items[m.cursor]
+}
+
+func (m *TasksModel) Render() string {
+	var list strings.Builder
+
+	for i, item := range m.items {
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "> "
+		}
+
+		check := "[ ]"
+		if item.Done {
+			check = "[x]"
+		}
+
+		title := item.Title
+		if item.Priority != "" {
+			title = fmt.Sprintf("(%s) %s", item.Priority, title)
+		}
+
+		pomodoros := strconv.Itoa(m.done[item.ID])
+		if item.Estimate != 0 {
+			pomodoros += "/" + strconv.Itoa(int(item.Estimate))
+		}
+
+		line := fmt.Sprintf("%s%s %s (%s)", cursor, check, title, pomodoros)
+		if item.ID == m.active {
+			line += " (active)"
+		}
+
+		list.WriteString(line + "\n")
+	}
+
+	if len(m.items) == 0 && !m.adding {
+		list.WriteString("No tasks yet\n")
+	}
+
+	if m.adding {
+		list.WriteString("New task: " + m.input.View() + "\n")
+	}
+
+	list.WriteString("\n[a] add [enter] pick [x] done [+/-] estimate")
+
+	if m.err != nil {
+		list.WriteString("\n\n" + GetErrorStyle().Render(m.err.Error()))
+	}
+
+	color := Config.ProgressBar.FocusColor
+
+	s := GetBorderStyle(color).Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render("Tasks"),
+			"",
+			list.String(),
+		),
+	)
+
+	return s
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Replaces a leading ~/ by the home directory
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return homeDir + "/" + rest
+	}
+
+	return path
+}
+
+
+// A todo.txt file (http://todotxt.org), the ID of a task is a hash of its text so editing the text
+// makes it another task, the same text a second time is numbered after its place. The estimate is kept as est:N in the text and a done task's priority as pri:X
+
+type todoTxtTasks struct {
+	path string
+}
+
+type todoLine struct {
+	done        bool
+	completed   string // completion date
+	priority    string
+	created     string // creation date
+	text        string
+}
+
+var (
+	todoPriorityRe = regexp.MustCompile(`^\(([A-Z])\) `)
+	todoDateRe     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} `)
+)
+
+func parseTodoLine(line string) todoLine {
+	var l todoLine
+
+	if rest, ok := strings.CutPrefix(line, "x "); ok {
+		l.done = true
+		line = rest
+
+		if todoDateRe.MatchString(line) {
+			l.completed = line[:10]
+			line = line[11:]
+		}
+	} else if match := todoPriorityRe.FindStringSubmatch(line); match != nil {
+		l.priority = match[1]
+		line = line[len(match[0]):]
+	}
+
+	if todoDateRe.MatchString(line) {
+		l.created = line[:10]
+		line = line[11:]
+	}
+
+	l.text = line
+
+	// A done task keeps its priority as pri:X
+	if l.done {
+		l.priority = todoTag(l.text, "pri")
+	}
+
+	return l
+}
+
+func (l todoLine) String() string {
+	var parts []string
+
+	if l.done {
+		parts = append(parts, "x")
+		if l.completed != "" {
+			parts = append(parts, l.completed)
+		}
+	} else if l.priority != "" {
+		parts = append(parts, "(" + l.priority + ")")
+	}
+
+	if l.created != "" {
+		parts = append(parts, l.created)
+	}
+
+	return strings.Join(append(parts, l.text), " ")
+}
+
+// The value of a key:value tag of the text
+func todoTag(text string, key string) string {
+	for _, word := range strings.Fields(text) {
+		if value, ok := strings.CutPrefix(word, key + ":"); ok {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// Sets a key:value tag of the text, an empty value removes it
+func setTodoTag(text string, key string, value string) string {
+	var words []string
+
+	for _, word := range strings.Fields(text) {
+		if !strings.HasPrefix(word, key + ":") {
+			words = append(words, word)
+		}
+	}
+
+	if value != "" {
+		words = append(words, key + ":" + value)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// The text without the tags plumadoro changes, it's what the ID is made of
+func (l todoLine) title() string {
+	return setTodoTag(setTodoTag(l.text, "est", ""), "pri", "")
+}
+
+// The ID of the occurrence-th line with this title, the first one isn't numbered
+func (l todoLine) id(occurrence int) string {
+	sum := sha1.Sum([]byte(l.title()))
+	id := "todo:" + hex.EncodeToString(sum[:4])
+
+	if occurrence > 1 {
+		id += fmt.Sprintf("-%d", occurrence)
+	}
+
+	return id
+}
+
+// Parses the lines with their IDs, the blank lines have no ID
+func parseTodoLines(lines []string) ([]todoLine, []string) {
+	parsed := make([]todoLine, len(lines))
+	ids    := make([]string, len(lines))
+	seen   := map[string]int{}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		parsed[i] = parseTodoLine(line)
+		title := parsed[i].title()
+		seen[title]++
+		ids[i] = parsed[i].id(seen[title])
+	}
+
+	return parsed, ids
+}
+
+func (t todoTxtTasks) read() ([]string, error) {
+	content, err := os.ReadFile(t.path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed reading the todo.txt file: %w", err)
+	}
+
+	content = bytes.TrimRight(content, "\n")
+	if len(content) == 0 {
+		return nil, nil
+	}
+
+	return strings.Split(string(content), "\n"), nil
+}
+
+func (t todoTxtTasks) write(lines []string) error {
+	// Written to a temporary file first so a failure doesn't lose the tasks
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines, "\n") + "\n"), 0644); err != nil {
+		return fmt.Errorf("Failed saving the todo.txt file: %w", err)
+	}
+
+	return os.Rename(tmp, t.path)
+}
+
+func (t todoTxtTasks) list() ([]taskItem, error) {
+	lines, err := t.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []taskItem
+	parsed, ids := parseTodoLines(lines)
+	for i, l := range parsed {
+		if ids[i] == "" {
+			continue
+		}
+
+		estimate, _ := strconv.ParseUint(todoTag(l.text, "est"), 10, 8)
+
+		items = append(items, taskItem{
+			ID:       ids[i],
+			Title:    l.title(),
+			Estimate: uint8(estimate),
+			Done:     l.done,
+			Priority: l.priority,
+			Tags:     parseTags(l.text),
+		})
+	}
+
+	return items, nil
+}
+
+// Changes the line of the task with the given ID and writes the file back
+func (t todoTxtTasks) update(id string, change func(l *todoLine)) error {
+	lines, err := t.read()
+	if err != nil {
+		return err
+	}
+
+	parsed, ids := parseTodoLines(lines)
+	for i, l := range parsed {
+		if ids[i] != id {
+			continue
+		}
+
+		change(&l)
+		lines[i] = l.String()
+
+		return t.write(lines)
+	}
+
+	return fmt.Errorf("%w: %q", ErrUnknownTask, id)
+}
+
+func (t todoTxtTasks) add(title string, now time.Time) error {
+	lines, err := t.read()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	l := todoLine{created: now.Format(time.DateOnly), text: title}
+	if match := todoPriorityRe.FindStringSubmatch(title); match != nil {
+		l.priority = match[1]
+		l.text = title[len(match[0]):]
+	}
+
+	return t.write(append(lines, l.String()))
+}
+
+func (t todoTxtTasks) setDone(id string, done bool, now time.Time) error {
+	return t.update(id, func(l *todoLine) {
+		if done == l.done {
+			return
+		}
+
+		l.done = done
+		if done {
+			l.completed = now.Format(time.DateOnly)
+			l.text = setTodoTag(l.text, "pri", l.priority)
+		} else {
+			l.completed = ""
+			l.text = setTodoTag(l.text, "pri", "")
+		}
+	})
+}
+
+func (t todoTxtTasks) setEstimate(id string, estimate uint8) error {
+	return t.update(id, func(l *todoLine) {
+		value := ""
+		if estimate != 0 {
+			value = strconv.Itoa(int(estimate))
+		}
+		l.text = setTodoTag(l.text, "est", value)
+	})
+}
+
+
+// Taskwarrior (https://taskwarrior.org) through its command, a task's project is shown as +project
+// and its tags as @tag like in todo.txt. The estimate is read from an "estimate" UDA if there is one
+
+type taskwarriorTasks struct {
+	command string
+}
+
+// What `task export` prints for every task
+type taskwarriorTask struct {
+	UUID         string    `json:"uuid"`
+	Description  string    `json:"description"`
+	Status       string    `json:"status"`
+	Priority     string    `json:"priority"`
+	Project      string    `json:"project"`
+	Tags         []string  `json:"tags"`
+	Urgency      float64   `json:"urgency"`
+	Estimate     float64   `json:"estimate"`
+}
+
+// Taskwarrior's priorities as todo.txt's
+var taskwarriorPriorities = map[string]string{"H": "A", "M": "B", "L": "C"}
+
+func (t taskwarriorTasks) run(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(t.command, append([]string{"rc.verbose=nothing", "rc.confirmation=off"}, args...)...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed running %s: %w %s", t.command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+func (t taskwarriorTasks) list() ([]taskItem, error) {
+	out, err := t.run("export")
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []taskwarriorTask
+	if err := json.Unmarshal(out, &tasks); err != nil {
+		return nil, fmt.Errorf("Failed parsing the tasks of %s: %w", t.command, err)
+	}
+
+	// The most urgent first like `task next`
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].Urgency > tasks[j].Urgency })
+
+	var items []taskItem
+	for _, task := range tasks {
+		if task.Status != "pending" && task.Status != "completed" {
+			continue // deleted, waiting and the templates of the recurring tasks
+		}
+
+		var tags []string
+		if task.Project != "" {
+			tags = append(tags, "+" + task.Project)
+		}
+		for _, tag := range task.Tags {
+			tags = append(tags, "@" + tag)
+		}
+
+		items = append(items, taskItem{
+			ID:       "tw:" + task.UUID,
+			Title:    task.Description,
+			Estimate: uint8(min(max(task.Estimate, 0), 99)),
+			Done:     task.Status == "completed",
+			Priority: taskwarriorPriorities[task.Priority],
+			Tags:     tags,
+		})
+	}
+
+	return items, nil
+}
+
+func (t taskwarriorTasks) uuid(id string) (string, error) {
+	uuid, ok := strings.CutPrefix(id, "tw:")
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownTask, id)
+	}
+
+	return uuid, nil
+}
+
+func (t taskwarriorTasks) add(title string, now time.Time) error {
+	_, err := t.run("add", title)
+	return err
+}
+
+func (t taskwarriorTasks) setDone(id string, done bool, now time.Time) error {
+	uuid, err := t.uuid(id)
+	if err != nil {
+		return err
+	}
+
+	if done {
+		_, err = t.run(uuid, "done")
+	} else {
+		_, err = t.run(uuid, "modify", "status:pending")
+	}
+
+	return err
+}
+
+func (t taskwarriorTasks) setEstimate(id string, estimate uint8) error {
+	return fmt.Errorf("%w: the estimates of Taskwarrior's tasks are set with its estimate UDA", ErrTaskNotSupported)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTodoLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		want  todoLine
+	}{
+		{"Call Bob", todoLine{text: "Call Bob"}},
+		{"(A) Write the report +work est:3", todoLine{priority: "A", text: "Write the report +work est:3"}},
+		{"(B) 2026-03-01 Call Bob @phone", todoLine{priority: "B", created: "2026-03-01", text: "Call Bob @phone"}},
+		{"x Call Bob", todoLine{done: true, text: "Call Bob"}},
+		{"x 2026-03-02 2026-03-01 Call Bob pri:C", todoLine{done: true, completed: "2026-03-02", created: "2026-03-01",
+			priority: "C", text: "Call Bob pri:C"}},
+		{"x 2026-03-02 Call Bob", todoLine{done: true, completed: "2026-03-02", text: "Call Bob"}},
+	}
+
+	for _, test := range tests {
+		got := parseTodoLine(test.line)
+		if got != test.want {
+			t.Errorf("parseTodoLine(%q) = %+v, want %+v", test.line, got, test.want)
+		}
+		if s := got.String(); s != test.line {
+			t.Errorf("String() = %q, want the line %q back", s, test.line)
+		}
+	}
+}
+
+// Writes a todo.txt file for the test
+func newTestTodoTxt(t *testing.T, lines ...string) todoTxtTasks {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "todo.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n") + "\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return todoTxtTasks{path: path}
+}
+
+func readTestTodoTxt(t *testing.T, tasks todoTxtTasks) []string {
+	t.Helper()
+
+	lines, err := tasks.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return lines
+}
+
+// Marking a task done and not done again keeps its priority, estimate and dates
+func TestTodoSetDone(t *testing.T) {
+	tasks := newTestTodoTxt(t, "(B) 2026-03-01 Call Bob est:2")
+	now := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.Local)
+
+	items, err := tasks.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[0].Title != "Call Bob" || items[0].Estimate != 2 || items[0].Priority != "B" {
+		t.Fatalf("list() = %+v, want Call Bob of priority B estimated to 2", items[0])
+	}
+	id := items[0].ID
+
+	steps := []struct {
+		change  func() error
+		line    string
+	}{
+		{func() error { return tasks.setDone(id, true, now) }, "x 2026-03-02 2026-03-01 Call Bob est:2 pri:B"},
+		{func() error { return tasks.setDone(id, false, now) }, "(B) 2026-03-01 Call Bob est:2"},
+		{func() error { return tasks.setEstimate(id, 4) }, "(B) 2026-03-01 Call Bob est:4"},
+		{func() error { return tasks.setEstimate(id, 0) }, "(B) 2026-03-01 Call Bob"},
+	}
+
+	for _, step := range steps {
+		if err := step.change(); err != nil {
+			t.Fatal(err)
+		}
+		if lines := readTestTodoTxt(t, tasks); lines[0] != step.line {
+			t.Errorf("line %q, want %q", lines[0], step.line)
+		}
+	}
+}
+
+// The same task written twice is two tasks, each changed on its own line
+func TestTodoDuplicates(t *testing.T) {
+	tasks := newTestTodoTxt(t, "Call Bob", "Water the plants", "Call Bob")
+
+	items, err := tasks.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[0].ID == items[2].ID {
+		t.Fatalf("list() = %+v, want 3 tasks with their own IDs", items)
+	}
+
+	if err := tasks.setDone(items[2].ID, true, time.Date(2026, time.March, 2, 10, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Call Bob", "Water the plants", "x 2026-03-02 Call Bob"}
+	if lines := readTestTodoTxt(t, tasks); strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines %q, want %q", lines, want)
+	}
+
+	// The IDs don't change once one of them is done
+	after, _ := tasks.list()
+	if after[0].ID != items[0].ID || after[2].ID != items[2].ID {
+		t.Errorf("IDs %s %s after marking one done, want %s %s", after[0].ID, after[2].ID, items[0].ID, items[2].ID)
+	}
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The webhooks are HTTP requests sent on the events of the timer. The requests are made when the
+// event happens and kept in the outbox file until they're delivered, so the events of a day offline
+// are sent once back online, even after a restart. A server answering with an error is retried with
+// a backoff until the webhook's retries run out, an unreachable one is retried until it's reachable
+
+// The events a webhook can be sent on
+var webhookEvents = []string{
+	PhaseStartedEvent, CompletedEvent, SkippedEvent, PausedEvent, ResumedEvent, ResetEvent,
+}
+
+// What the body template of a webhook gets, it's also the default body
+type webhookData struct {
+	hookPayload
+	Ends  time.Time  `json:"ends"` // when the phase ends if it runs from the time of the event
+}
+
+// A request waiting in the outbox
+type webhookDelivery struct {
+	Webhook   string             `json:"webhook"` // the name
+	Event     string             `json:"event"`
+	Method    string             `json:"method"`
+	URL       string             `json:"url"`
+	Headers   map[string]string  `json:"headers"`
+	Body      string             `json:"body"`
+	Timeout   time.Duration      `json:"timeout"`
+	Retries   uint8              `json:"retries"`
+	Backoff   time.Duration      `json:"backoff"`
+
+	Attempts  uint16             `json:"attempts"`
+	Errors    uint8              `json:"errors"` // the attempts the server answered with an error
+	Next      time.Time          `json:"next"` // it's not sent before it
+}
+
+type outbox struct {
+	mu          sync.Mutex
+	path        string
+	deliveries  []webhookDelivery // in the order of their events
+	wake        chan struct{}
+	warnings    chan string // the failures to show
+	client      *http.Client
+}
+
+// Sent to the TUI when a webhook failed
+type WebhookWarningMsg struct {
+	Content string
+}
+
+var (
+	ErrInvalidWebhook      = errors.New("Invalid webhook")
+	ErrWebhookRejected     = errors.New("Webhook rejected")
+	ErrFailedReadingOutbox = errors.New("Failed reading the webhooks outbox")
+	ErrFailedWritingOutbox = errors.New("Failed writing the webhooks outbox")
+)
+
+const webhookMaxBackoff time.Duration = time.Minute * 15
+
+var (
+	webhooks      *outbox // nil until getOutbox is called
+	webhooksOnce  sync.Once
+)
+
+// The outbox is next to the log, so the one of --log is another one
+func outboxPath() string {
+	return filepath.Join(filepath.Dir(logPath), ".plumadoro_outbox.json")
+}
+
+func newOutbox(path string) *outbox {
+	return &outbox{
+		path:     path,
+		wake:     make(chan struct{}, 1),
+		warnings: make(chan string, 16),
+		client:   &http.Client{},
+	}
+}
+
+// Loads the outbox and starts delivering it the first time it's called, after the log's path is set
+func getOutbox() *outbox {
+	webhooksOnce.Do(func() {
+		webhooks = newOutbox(outboxPath())
+		if err := webhooks.load(); err != nil {
+			webhooks.warn(err.Error())
+		}
+		go webhooks.loop()
+	})
+
+	return webhooks
+}
+
+func validateWebhooks(errsPtr *[]error) {
+	var valid []WebhookConfigT
+
+	for i, w := range Config.Webhooks {
+		key := fmt.Sprintf("webhooks[%d]", i)
+
+		u, err := url.Parse(w.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			*errsPtr = append(*errsPtr, fmt.Errorf("%w: %s.url must be an http or https URL", ErrInvalidWebhook, key))
+			continue
+		}
+
+		if w.Body != "" {
+			w.template, err = template.New(key).Option("missingkey=error").Funcs(template.FuncMap{
+				"json": func(v any) (string, error) {
+					data, err := json.Marshal(v)
+					return string(data), err
+				},
+			}).Parse(w.Body)
+			if err != nil {
+				*errsPtr = append(*errsPtr, fmt.Errorf("%w: %s.body: %w", ErrInvalidWebhook, key, err))
+				continue
+			}
+		}
+
+		if w.Name == "" {
+			w.Name = u.Host
+		}
+
+		if w.Events == nil {
+			w.Events = []string{PhaseStartedEvent, CompletedEvent, SkippedEvent}
+		}
+		for j := range w.Events {
+			validateOption(errsPtr, &w.Events[j],
+				&webhookEvents,
+				PhaseStartedEvent, fmt.Sprintf("%s.events[%d]", key, j))
+		}
+
+		if w.Method == "" {
+			w.Method = http.MethodPost
+		}
+		validateOption(errsPtr, &w.Method,
+			&[]string{http.MethodPost, http.MethodPut, http.MethodPatch},
+			http.MethodPost, key + ".method")
+
+		if w.Timeout == 0 {
+			w.Timeout = time.Second * 10
+		}
+		validateRange(errsPtr, &w.Timeout,
+			time.Millisecond*100, time.Minute*5,
+			time.Second*10, key + ".timeout")
+
+		if w.Retries == 0 {
+			w.Retries = 5
+		}
+		validateRange(errsPtr, &w.Retries,
+			1, 50,
+			5, key + ".retries")
+
+		if w.Backoff == 0 {
+			w.Backoff = time.Second * 2
+		}
+		validateRange(errsPtr, &w.Backoff,
+			time.Millisecond*100, webhookMaxBackoff,
+			time.Second * 2, key + ".backoff")
+
+		valid = append(valid, w)
+	}
+
+	Config.Webhooks = valid
+}
+
+// The request of the webhook for the event
+func (w *WebhookConfigT) delivery(data webhookData) (webhookDelivery, error) {
+	var body []byte
+	var err error
+
+	if w.template != nil {
+		var b bytes.Buffer
+		err = w.template.Execute(&b, data)
+		body = b.Bytes()
+	} else {
+		body, err = json.Marshal(data)
+	}
+	if err != nil {
+		return webhookDelivery{}, fmt.Errorf("%w: %s: %w", ErrInvalidWebhook, w.Name, err)
+	}
+
+	if !json.Valid(body) {
+		return webhookDelivery{}, fmt.Errorf("%w: %s: the body isn't valid JSON: %s", ErrInvalidWebhook, w.Name, body)
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	for k, v := range w.Headers {
+		headers[k] = os.ExpandEnv(v) // so the tokens can stay out of the config
+	}
+
+	return webhookDelivery{
+		Webhook: w.Name,
+		Event:   data.Event,
+		Method:  w.Method,
+		URL:     w.URL,
+		Headers: headers,
+		Body:    string(body),
+		Timeout: w.Timeout,
+		Retries: w.Retries,
+		Backoff: w.Backoff,
+		Next:    data.Time,
+	}, nil
+}
+
+// Queues the webhooks of the events, they're sent in the background. The daemon sends them when
+// the TUI is attached to one
+func (m *PomodoroModel) queueWebhooks(events []pomodoroEvent) {
+	if len(Config.Webhooks) == 0 || m.remote != nil {
+		return
+	}
+
+	var deliveries []webhookDelivery
+
+	for _, e := range events {
+		for i := range Config.Webhooks {
+			w := &Config.Webhooks[i]
+			if !slices.Contains(w.Events, e.event) {
+				continue
+			}
+
+			payload := m.payload(e)
+			d, err := w.delivery(webhookData{
+				hookPayload: payload,
+				Ends:        payload.Time.Add(e.remainingTime),
+			})
+			if err != nil {
+				getOutbox().warn(err.Error())
+				continue
+			}
+
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	if len(deliveries) != 0 {
+		getOutbox().queue(deliveries)
+	}
+}
+
+// Waits for a webhook to fail
+func waitWebhookWarning() tea.Cmd {
+	warnings := getOutbox().warnings
+	return func() tea.Msg { return WebhookWarningMsg{Content: <-warnings} }
+}
+
+func (o *outbox) warn(content string) {
+	select {
+	case o.warnings <- content:
+	default: // nobody is taking them
+	}
+}
+
+func (o *outbox) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default: // the loop is already woken up
+	}
+}
+
+func (o *outbox) load() error {
+	data, err := os.ReadFile(o.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedReadingOutbox, err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := json.Unmarshal(data, &o.deliveries); err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedReadingOutbox, err)
+	}
+
+	return nil
+}
+
+// Writes the outbox to a temporary file then renames it so it's never half written. It's called
+// with mu held
+func (o *outbox) save() error {
+	data, err := json.MarshalIndent(o.deliveries, "", "\t")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedWritingOutbox, err)
+	}
+
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedWritingOutbox, err)
+	}
+	if err := os.Rename(tmp, o.path); err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedWritingOutbox, err)
+	}
+
+	return nil
+}
+
+func (o *outbox) queue(deliveries []webhookDelivery) {
+	o.mu.Lock()
+	o.deliveries = append(o.deliveries, deliveries...)
+	err := o.save()
+	o.mu.Unlock()
+
+	if err != nil {
+		o.warn(err.Error())
+	}
+	o.signal()
+}
+
+// The index of the next delivery to send, the ones of a webhook are sent in their order. When none
+// is due it's -1 with how long to wait for the next one, 0 when the outbox is empty
+func (o *outbox) next(now time.Time) (int, time.Duration) {
+	var wait time.Duration
+	var seen []string // the webhooks with an earlier delivery
+
+	for i, d := range o.deliveries {
+		if slices.Contains(seen, d.Webhook) {
+			continue
+		}
+		seen = append(seen, d.Webhook)
+
+		if !d.Next.After(now) {
+			return i, 0
+		}
+		if left := d.Next.Sub(now); wait == 0 || left < wait {
+			wait = left
+		}
+	}
+
+	return -1, wait
+}
+
+// Sends the deliveries as they're due, it's the only one removing them from the outbox
+func (o *outbox) loop() {
+	for {
+		o.mu.Lock()
+		i, wait := o.next(time.Now())
+		var d webhookDelivery
+		if i != -1 {
+			d = o.deliveries[i]
+		}
+		o.mu.Unlock()
+
+		if i != -1 {
+			o.deliver(i, d)
+			continue
+		}
+
+		var timer <-chan time.Time
+		if wait != 0 {
+			timer = time.After(wait)
+		}
+
+		select {
+		case <-o.wake:
+		case <-timer:
+		}
+	}
+}
+
+// Sends the delivery at index i then removes it from the outbox or schedules its retry
+func (o *outbox) deliver(i int, d webhookDelivery) {
+	err := o.send(d)
+
+	var rejected bool
+	var warning string
+
+	d.Attempts++
+	if err != nil {
+		rejected = errors.Is(err, ErrWebhookRejected)
+		if rejected {
+			d.Errors++
+		}
+
+		// The first failure is shown and the delivery giving up, not every retry
+		switch {
+		case rejected && d.Errors > d.Retries:
+			warning = fmt.Sprintf("Webhook %s gave up on the %s event after %d attempts: %s",
+				d.Webhook, d.Event, d.Attempts, err)
+		case d.Attempts == 1 && rejected:
+			warning = fmt.Sprintf("Webhook %s failed, it will be retried: %s", d.Webhook, err)
+		case d.Attempts == 1:
+			warning = fmt.Sprintf("Webhook %s is unreachable, it's kept until it's sent: %s", d.Webhook, err)
+		}
+	}
+
+	o.mu.Lock()
+	if err == nil || (rejected && d.Errors > d.Retries) {
+		o.deliveries = slices.Delete(o.deliveries, i, i + 1)
+	} else {
+		backoff := d.Backoff << min(d.Attempts - 1, 16)
+		d.Next = time.Now().Add(min(backoff, webhookMaxBackoff))
+		o.deliveries[i] = d
+	}
+	saveErr := o.save()
+	o.mu.Unlock()
+
+	if warning != "" {
+		o.warn(warning)
+	}
+	if saveErr != nil {
+		o.warn(saveErr.Error())
+	}
+}
+
+// Makes the request, the error wraps ErrWebhookRejected when the server answered with an error
+func (o *outbox) send(d webhookDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, d.Method, d.URL, strings.NewReader(d.Body))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrWebhookRejected, err) // it won't get better by retrying
+	}
+	for k, v := range d.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		if s := strings.TrimSpace(string(msg)); s != "" {
+			return fmt.Errorf("%w: %s: %s", ErrWebhookRejected, resp.Status, s)
+		}
+		return fmt.Errorf("%w: %s", ErrWebhookRejected, resp.Status)
+	}
+
+	return nil
+}
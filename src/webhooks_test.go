@@ -0,0 +1,167 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A server answering with status and recording the requests it got
+func newWebhookServer(t *testing.T, status int) (*httptest.Server, chan *http.Request, chan string) {
+	t.Helper()
+
+	requests := make(chan *http.Request, 16)
+	bodies := make(chan string, 16)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- string(body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests, bodies
+}
+
+func newTestOutbox(t *testing.T) *outbox {
+	return newOutbox(filepath.Join(t.TempDir(), ".plumadoro_outbox.json"))
+}
+
+// A delivery of the webhooks' config to url
+func testDelivery(t *testing.T, url string, body string, headers map[string]string) webhookDelivery {
+	t.Helper()
+
+	config := defaultConfig
+	config.Webhooks = []WebhookConfigT{{Name: "test", URL: url, Body: body, Headers: headers, Retries: 2}}
+	useConfig(t, config)
+
+	var errs []error
+	validateWebhooks(&errs)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	now := newFakeClock().Now()
+	d, err := Config.Webhooks[0].delivery(webhookData{
+		hookPayload: hookPayload{Event: PhaseStartedEvent, Time: now, Phase: "focus", N: 1},
+		Ends:        now.Add(time.Minute * 25),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Backoff = time.Millisecond * 10
+
+	return d
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	server, requests, bodies := newWebhookServer(t, http.StatusOK)
+	t.Setenv("PLUMADORO_TEST_TOKEN", "secret")
+
+	d := testDelivery(t, server.URL,
+		`{"text": {{json (printf "in %s until %s" .Phase (.Ends.Format "15:04"))}}}`,
+		map[string]string{"Authorization": "Bearer $PLUMADORO_TEST_TOKEN"})
+
+	o := newTestOutbox(t)
+	o.client = server.Client()
+	o.queue([]webhookDelivery{d})
+	o.deliver(0, o.deliveries[0])
+
+	r := <-requests
+	if body := <-bodies; body != `{"text": "in focus until 09:25"}` {
+		t.Errorf("body %s, want the template's", body)
+	}
+	if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization %q, want the token of the environment", got)
+	}
+	if got := r.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type %q, want application/json", got)
+	}
+	if r.Method != http.MethodPost {
+		t.Errorf("method %s, want POST", r.Method)
+	}
+
+	if len(o.deliveries) != 0 {
+		t.Errorf("%d deliveries left in the outbox, want none", len(o.deliveries))
+	}
+}
+
+// A server answering with an error is retried with a backoff doubling each time, until the retries
+// run out
+func TestWebhookRetries(t *testing.T) {
+	server, requests, _ := newWebhookServer(t, http.StatusInternalServerError)
+
+	o := newTestOutbox(t)
+	o.client = server.Client()
+	o.queue([]webhookDelivery{testDelivery(t, server.URL, "", nil)})
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		before := time.Now()
+		o.deliver(0, o.deliveries[0])
+		<-requests
+
+		if attempt == 3 {
+			break
+		}
+
+		d := o.deliveries[0]
+		backoff := time.Millisecond * 10 << (attempt - 1)
+		if d.Attempts != uint16(attempt) || d.Errors != uint8(attempt) || d.Next.Before(before.Add(backoff)) {
+			t.Errorf("attempt %d: %d attempts %d errors next in %s, want a backoff of %s", attempt,
+				d.Attempts, d.Errors, d.Next.Sub(before), backoff)
+		}
+
+		if i, wait := o.next(before); i != -1 || wait <= 0 {
+			t.Errorf("attempt %d: next() = %d, %s, want to wait for the backoff", attempt, i, wait)
+		}
+	}
+
+	if len(o.deliveries) != 0 {
+		t.Errorf("%d deliveries left after the retries ran out, want none", len(o.deliveries))
+	}
+
+	var warnings []string
+	for len(o.warnings) != 0 {
+		warnings = append(warnings, <-o.warnings)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "will be retried") ||
+		!strings.Contains(warnings[1], "gave up") {
+		t.Errorf("warnings %q, want the first failure and the giving up", warnings)
+	}
+}
+
+// An unreachable server is kept in the outbox, also after a restart
+func TestWebhookUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	o := newTestOutbox(t)
+	d := testDelivery(t, url, "", nil)
+	d.Retries = 1
+	o.queue([]webhookDelivery{d})
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		o.deliver(0, o.deliveries[0])
+	}
+
+	if len(o.deliveries) != 1 || o.deliveries[0].Attempts != 3 || o.deliveries[0].Errors != 0 {
+		t.Fatalf("outbox %+v, want the delivery with 3 attempts and no errors", o.deliveries)
+	}
+	if warning := <-o.warnings; !strings.Contains(warning, "unreachable") || len(o.warnings) != 0 {
+		t.Errorf("warning %q, want only the first failure", warning)
+	}
+
+	reloaded := newOutbox(o.path)
+	if err := reloaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.deliveries) != 1 || reloaded.deliveries[0].URL != url || reloaded.deliveries[0].Attempts != 3 {
+		t.Errorf("reloaded outbox %+v, want the undelivered request", reloaded.deliveries)
+	}
+}